@@ -2,11 +2,13 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"strconv"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"text/tabwriter"
 
 	"github.com/gookit/color"
@@ -15,22 +17,42 @@ import (
 )
 
 type CLI struct {
-	TargetURL             string // target URL
-	Concurrency           int    // number of concurrent requests
-	Timeout               int    // Request timeout duration (in seconds)
-	Delay                 int    // delay between each request (in ms)
-	DelayJitter           int    // maximum jitter to add to delay (in ms)
-	ResponseHeaderTimeout int    // Response header timeout duration (in seconds)
-	UserAgent             string // custom user-agent
-	Proxy                 string // proxy URL (e.g., 127.0.0.1:8080)
-	Infile                string // file containin targets (newline separated)
-	Outfile               string // file to write results
-	ResolversFile         string // file containing DNS resolvers
-	HideClaimed           bool   // hide claimed packages
-	Verbose               bool   // hide info
-	Silence               bool   // suppress output from console
-	Version               bool   // print version
+	TargetURL             string  // target URL
+	Concurrency           int     // number of concurrent requests
+	Timeout               int     // Request timeout duration (in seconds)
+	Delay                 int     // delay between each request (in ms)
+	DelayJitter           int     // maximum jitter to add to delay (in ms)
+	ResponseHeaderTimeout int     // Response header timeout duration (in seconds)
+	UserAgent             string  // custom user-agent
+	Proxy                 string  // proxy URL (e.g., 127.0.0.1:8080)
+	Infile                string  // file containin targets (newline separated)
+	Path                  string  // local files or directories to scan (comma separated)
+	IgnoreDirs            string  // directory names to skip when scanning directories (comma separated)
+	SkipNodeModules       bool    // skip node_modules directories when scanning directories
+	Outfile               string  // file to write results
+	ResolversFile         string  // file containing DNS resolvers
+	Registries            string  // public registry URLs (comma separated)
+	InternalRegistries    string  // internal registry URLs (comma separated)
+	RegistriesFile        string  // file containing registry definitions
+	CacheFile             string  // file to persist registry lookups to
+	CacheTTL              int     // lifetime of cached registry lookups (in hours)
+	VisitedFile           string  // file to persist visited urls and files to
+	JSON                  bool    // output results as a JSON array
+	JSONL                 bool    // output results as JSON Lines
+	CSV                   bool    // output results as CSV
+	HideClaimed           bool    // hide claimed packages
+	MinConfidence         float64 // skip packages detected with lower confidence
+	NodeVersion           string  // Node.js release whose builtin modules are skipped
+	CrawlDepth            int     // levels of scripts loaded by HTML pages to fetch
+	CrawlHosts            string  // hosts other than the page's own to crawl (comma separated)
+	Verbose               bool    // hide info
+	Silence               bool    // suppress output from console
+	Version               bool    // print version
 	Writer                *tabwriter.Writer
+	Output                resultWriter // writes results to the outfile, or to stdout for machine readable formats
+	outputFile            *os.File
+	visited               *npmjack.FileVisited // visited file, closed on a forced exit
+	closeOnce             sync.Once
 	Help                  bool // print help
 }
 
@@ -55,6 +77,45 @@ func main() {
 	runner.Options.Proxy = cli.Proxy
 	runner.Options.Verbose = cli.Verbose
 	runner.Options.Silence = cli.Silence
+	runner.Options.CacheFile = cli.CacheFile
+	runner.Options.CacheTTL = cli.CacheTTL
+	runner.Options.IgnoreDirs = splitList(cli.IgnoreDirs)
+	runner.Options.SkipNodeModules = cli.SkipNodeModules
+	runner.Options.MinConfidence = cli.MinConfidence
+	runner.Options.CrawlDepth = cli.CrawlDepth
+	runner.Options.CrawlHosts = splitList(cli.CrawlHosts)
+
+	if cli.MinConfidence < 0 || cli.MinConfidence > 1 {
+		log.Errorf("--min-confidence must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	if cli.CrawlDepth < 0 {
+		log.Errorf("--crawl-depth must not be negative")
+		os.Exit(1)
+	}
+
+	if cli.NodeVersion != "" {
+		if runner.Options.NodeVersion, err = npmjack.ParseNodeVersion(cli.NodeVersion); err != nil {
+			log.Errorf("Error parsing --node-version: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if cli.hasRegistries() {
+		if runner.Options.Registries, err = cli.getRegistries(); err != nil {
+			log.Errorf("Error reading registries: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if cli.VisitedFile != "" {
+		if cli.visited, err = npmjack.NewFileVisited(cli.VisitedFile); err != nil {
+			log.Errorf("Error opening visited file: %v", err)
+			os.Exit(1)
+		}
+		runner.Visited = cli.visited
+	}
 
 	if cli.hasResolversFile() {
 		if runner.Options.Resolvers, err = cli.readFileLines(cli.ResolversFile); err != nil {
@@ -62,24 +123,36 @@ func main() {
 		}
 	}
 
+	if err = cli.openOutput(); err != nil {
+		log.Errorf("could not open file: %v", err)
+		os.Exit(1)
+	}
+	defer cli.closeOutput()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cli.handleInterrupt(cancel)
+
 	cli.Writer = tabwriter.NewWriter(os.Stdout, 27, 0, 0, ' ', tabwriter.TabIndent)
-	if !cli.Silence && !cli.Verbose {
+	if cli.hasTable() && !cli.Verbose {
 		fmt.Println("")
-		fmt.Fprintln(cli.Writer, "\tPACKAGE\tNAMESPACE            CLAIMED   SOURCE\t")
-		fmt.Fprintln(cli.Writer, "\t-------\t---------            -------   ------\t")
+		fmt.Fprintln(cli.Writer, "\tPACKAGE\tNAMESPACE            CLAIMED   SCOPE     CONF  METHOD                  SOURCE\t")
+		fmt.Fprintln(cli.Writer, "\t-------\t---------            -------   -----     ----  ------                  ------\t")
 	}
 
 	var wg sync.WaitGroup
+	wg.Add(1)
+	cli.processResults(runner, &wg)
+	defer wg.Wait()
+	defer runner.Close()
+
+	if cli.hasPath() {
+		cli.scanPaths(ctx, runner)
+		return
+	}
+
 	if cli.hasStdin() {
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			url := scanner.Text()
-			wg.Add(1)
-			cli.processResults(runner, &wg)
-			runner.Run(url)
-		}
-		runner.Close()
-		wg.Wait()
+		cli.scanStdin(ctx, runner)
 		return
 	} else if cli.hasInfile() {
 		if targets, err = cli.readFileLines(cli.Infile); err != nil {
@@ -90,13 +163,7 @@ func main() {
 	}
 
 	if len(targets) > 0 {
-		for _, target := range targets {
-			wg.Add(1)
-			cli.processResults(runner, &wg)
-			runner.Run(target)
-		}
-		runner.Close()
-		wg.Wait()
+		runner.RunContext(ctx, targets...)
 	}
 }
 
@@ -109,30 +176,124 @@ func (c *CLI) processResults(runner *npmjack.Runner, wg *sync.WaitGroup) {
 	go func() {
 		defer wg.Done()
 		for result := range runner.Results {
-			if !c.Silence {
+			if c.Output != nil {
+				if err := c.Output.write(c.filterResult(result)); err != nil {
+					log.Errorf("could not write result: %v", err)
+				}
+			}
+			if c.hasTable() {
 				if result.Packages != nil {
 					for _, pkg := range result.Packages {
-						if pkg.Namespace == " " {
-							pkg.Namespace = strings.Repeat(" ", 15) // create a string with 15 blank spaces
-						}
-
-						if pkg.Claimed {
-							if !c.HideClaimed {
-								fmt.Fprintf(c.Writer, "%s\t%-12s         %-12s%-35s %s\n", pkg.Name, pkg.Namespace, "Yes", result.RequestURL, result.Resolver)
-							}
-						} else {
-							fmt.Fprintf(c.Writer, "%s\t%-12s         %-12s%-35s %s\n", pkg.Name, pkg.Namespace, "No", result.RequestURL, result.Resolver)
+						if pkg.IsClaimed() && c.HideClaimed {
+							continue
 						}
+						fmt.Fprintf(c.Writer, "%s\t%-21s%-10s%-10s%-6.2f%-24s%-35s %s\n", pkg.FullName(), namespaceLabel(pkg), claimLabel(pkg.Status), scopeLabel(pkg), pkg.Confidence, firstMethodLabel(pkg), sourceLabel(result, pkg), result.Resolver)
 					}
+					c.Writer.Flush()
 				}
 			}
-			if c.hasOutfile() {
-				c.writeToFile([]string{strconv.Itoa(result.StatusCode) + " " + result.RequestURL})
-			}
 		}
 	}()
+}
 
-	c.Writer.Flush()
+// claimLabel returns the CLAIMED column value for the given claim status
+func claimLabel(status npmjack.ClaimStatus) string {
+	switch status {
+	case npmjack.StatusClaimed:
+		return "Yes"
+	case npmjack.StatusUnclaimed:
+		return "No"
+	case npmjack.StatusRateLimited:
+		return "Limited"
+	default:
+		return "Unknown"
+	}
+}
+
+// namespaceLabel returns the NAMESPACE column value for the given package,
+// "private scope" for scope findings and "private registry" for registry
+// findings
+func namespaceLabel(pkg npmjack.Package) string {
+	if pkg.IsRegistryOnly() {
+		return "private registry"
+	}
+	if !pkg.IsScoped() {
+		return ""
+	}
+	if pkg.IsScopeOnly() {
+		return "private scope"
+	}
+	return pkg.Namespace + "/"
+}
+
+// scopeLabel returns the SCOPE column value for the given package
+func scopeLabel(pkg npmjack.Package) string {
+	if !pkg.IsScoped() {
+		return "-"
+	}
+	return claimLabel(pkg.ScopeStatus)
+}
+
+// methodLabel returns the detection methods of the package, or "-" if unknown
+func methodLabel(pkg npmjack.Package) string {
+	if methods := pkg.Methods(); len(methods) > 0 {
+		return strings.Join(methods, ",")
+	}
+	return "-"
+}
+
+// firstMethodLabel returns the METHOD column value: the method of the first
+// detection, or "-" if unknown
+func firstMethodLabel(pkg npmjack.Package) string {
+	if len(pkg.Evidence) == 0 {
+		return "-"
+	}
+	return pkg.Evidence[0].Method
+}
+
+// positionLabel returns where the package was first found: "<line>:<column>"
+// in the result's source, "<source>:<line>:<column>" for files inside a
+// source map, or "-" if unknown
+func positionLabel(result npmjack.Result, pkg npmjack.Package) string {
+	if len(pkg.Evidence) == 0 || pkg.Evidence[0].Position() == "" {
+		return "-"
+	}
+
+	evidence := pkg.Evidence[0]
+	if evidence.Source != result.RequestURL {
+		return evidence.Source + ":" + evidence.Position()
+	}
+	return evidence.Position()
+}
+
+// sourceLabel returns the SOURCE column value: the request URL followed by
+// the position of the first detection
+func sourceLabel(result npmjack.Result, pkg npmjack.Package) string {
+	position := positionLabel(result, pkg)
+	switch {
+	case position == "-":
+		return result.RequestURL
+	case len(pkg.Evidence) > 0 && pkg.Evidence[0].Source != result.RequestURL:
+		return result.RequestURL + " (" + position + ")"
+	default:
+		return result.RequestURL + ":" + position
+	}
+}
+
+// filterResult removes packages that should not be reported
+func (c *CLI) filterResult(result npmjack.Result) npmjack.Result {
+	if !c.HideClaimed {
+		return result
+	}
+
+	var packages []npmjack.Package
+	for _, pkg := range result.Packages {
+		if !pkg.IsClaimed() {
+			packages = append(packages, pkg)
+		}
+	}
+	result.Packages = packages
+	return result
 }
 
 func (c *CLI) initialize() {
@@ -140,18 +301,76 @@ func (c *CLI) initialize() {
 	c.checkForExits()
 }
 
-// writeToFile writes the given lines to the given file
-func (c *CLI) writeToFile(lines []string) {
-	file, err := os.OpenFile(c.Outfile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Errorf("could not open file: %v", err)
+// openOutput opens the outfile, if any, and the writer for the chosen format.
+// Machine readable formats are written to stdout when no outfile is given
+func (c *CLI) openOutput() error {
+	if c.hasOutfile() {
+		file, err := os.OpenFile(c.Outfile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		c.outputFile = file
+		c.Output = newResultWriter(file, c.format())
+	} else if c.format() != "text" {
+		c.Output = newResultWriter(os.Stdout, c.format())
 	}
-	defer file.Close()
+	return nil
+}
+
+// closeOutput flushes the table and closes the output writer and outfile.
+// It is safe to call more than once
+func (c *CLI) closeOutput() {
+	c.closeOnce.Do(func() {
+		if c.Writer != nil {
+			c.Writer.Flush()
+		}
+		if c.Output != nil {
+			if err := c.Output.close(); err != nil {
+				log.Errorf("could not write results: %v", err)
+			}
+		}
+		if c.outputFile != nil {
+			if err := c.outputFile.Close(); err != nil {
+				log.Errorf("could not close file: %v", err)
+			}
+		}
+	})
+}
 
-	for i := range lines {
-		if _, err := file.WriteString(lines[i] + "\n"); err != nil {
-			log.Errorf("could not write line to file: %v", err)
+// handleInterrupt stops the scan when the user interrupts it. In-flight
+// requests are aborted and discarded, results of finished scans are still
+// written before exiting. A second interrupt closes the output and exits
+// immediately
+func (c *CLI) handleInterrupt(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Warnf("Interrupted, stopping scan (interrupt again to exit immediately)")
+		cancel()
+
+		<-signals
+		c.closeOutput()
+		if c.visited != nil {
+			if err := c.visited.Close(); err != nil {
+				log.Errorf("could not close visited file: %v", err)
+			}
 		}
+		os.Exit(130)
+	}()
+}
+
+// format returns the chosen output format
+func (c *CLI) format() string {
+	switch {
+	case c.JSON:
+		return "json"
+	case c.JSONL:
+		return "jsonl"
+	case c.CSV:
+		return "csv"
+	default:
+		return "text"
 	}
 }
 
@@ -167,13 +386,98 @@ func (c *CLI) checkForExits() {
 		os.Exit(0)
 	}
 
-	if !c.hasStdin() && !c.hasInfile() && !c.hasTarget() {
+	if !c.hasStdin() && !c.hasInfile() && !c.hasTarget() && !c.hasPath() {
 		fmt.Println("")
 		color.Redf("%s\n\n", "Missing target")
 		c.usage()
 	}
 }
 
+// getRegistries returns the registries given by --registry, --internal-registry and --registry-file
+func (c *CLI) getRegistries() (registries []npmjack.Registry, err error) {
+	for _, url := range splitList(c.Registries) {
+		registries = append(registries, npmjack.Registry{URL: url, Role: npmjack.RolePublic})
+	}
+
+	for _, url := range splitList(c.InternalRegistries) {
+		registries = append(registries, npmjack.Registry{URL: url, Role: npmjack.RoleInternal})
+	}
+
+	if c.RegistriesFile != "" {
+		lines, err := c.readFileLines(c.RegistriesFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			registry, err := npmjack.ParseRegistry(line)
+			if err != nil {
+				return nil, err
+			}
+			registries = append(registries, registry)
+		}
+	}
+
+	return
+}
+
+// splitList splits a comma separated flag value
+func splitList(value string) (items []string) {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+// scanStdin scans urls read from stdin as they arrive. Lines are handed to
+// concurrent workers so slow targets don't hold up the rest of the input
+func (c *CLI) scanStdin(ctx context.Context, runner *npmjack.Runner) {
+	lines := make(chan string)
+	var wg sync.WaitGroup
+
+	for i := 0; i < max(c.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range lines {
+				runner.RunContext(ctx, url)
+			}
+		}()
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for ctx.Err() == nil && scanner.Scan() {
+		select {
+		case lines <- scanner.Text():
+		case <-ctx.Done():
+		}
+	}
+	close(lines)
+	wg.Wait()
+}
+
+// scanPaths scans the local files and directories given by --path
+func (c *CLI) scanPaths(ctx context.Context, runner *npmjack.Runner) {
+	for _, path := range splitList(c.Path) {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Errorf("%v", err)
+			continue
+		}
+
+		if info.IsDir() {
+			runner.ScanDirContext(ctx, path)
+		} else {
+			runner.ScanFileContext(ctx, path)
+		}
+	}
+}
+
 // getTargets returns the targets to be used for the scan
 func (c *CLI) getTargets() (targets []string) {
 	if c.hasTarget() {
@@ -229,11 +533,26 @@ func (c *CLI) hasInfile() bool {
 	return c.Infile != ""
 }
 
+// hasPath determines if the user has provided local paths to scan
+func (c *CLI) hasPath() bool {
+	return c.Path != ""
+}
+
 // hasOutfile determines if the user has provided an output file
 func (c *CLI) hasOutfile() bool {
 	return c.Outfile != ""
 }
 
+// hasRegistries determines if the user has configured registries
+func (c *CLI) hasRegistries() bool {
+	return c.Registries != "" || c.InternalRegistries != "" || c.RegistriesFile != ""
+}
+
+// hasTable determines if results should be printed as a table on stdout
+func (c *CLI) hasTable() bool {
+	return !c.Silence && (c.hasOutfile() || c.format() == "text")
+}
+
 // hasResolversFile determines if the user has provided a resolvers file
 func (c *CLI) hasResolversFile() bool {
 	return c.ResolversFile != ""
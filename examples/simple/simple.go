@@ -21,7 +21,7 @@ func main() {
 		for result := range npmjack.Results {
 			if result.StatusCode == 200 {
 				for _, pkg := range result.Packages {
-					fmt.Println("Package", pkg.Name, "on", result.RequestURL, "Claimed:", pkg.Claimed)
+					fmt.Println("Package", pkg.FullName(), "on", result.RequestURL, "Status:", pkg.Status)
 				}
 			}
 		}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	npmjack "github.com/root4loot/npmjack/pkg/runner"
@@ -16,11 +17,12 @@ func (c *CLI) banner() {
 func (c *CLI) usage() {
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 3, ' ', 0)
 
-	fmt.Fprintf(w, "Usage:\t%s [options] (-u <url> | -i <targets.txt>)\n\n", os.Args[0])
+	fmt.Fprintf(w, "Usage:\t%s [options] (-u <url> | -i <targets.txt> | -f <path>)\n\n", os.Args[0])
 
 	fmt.Fprintf(w, "\nTARGETING:\n")
 	fmt.Fprintf(w, "\t%s,  %s\t\t\t%s\n", "-u", "--url", "target URL")
 	fmt.Fprintf(w, "\t%s,  %s\t\t\t%s\n", "-i", "--infile", "file containing URL's (newline separated)")
+	fmt.Fprintf(w, "\t%s,  %s\t\t\t%s\n", "-f", "--path", "local files or directories to scan (comma separated)")
 
 	fmt.Fprintf(w, "\nCONFIGURATIONS:\n")
 	fmt.Fprintf(w, "\t%s,  %s\t%s\t(Default: %d)\n", "-c", "--concurrency", "number of concurrent requests", npmjack.DefaultOptions().Concurrency)
@@ -30,10 +32,25 @@ func (c *CLI) usage() {
 	fmt.Fprintf(w, "\t%s, %s\t%s\t(Default: %d %s)\n", "-dj", "--delay-jitter", "max jitter between requests", npmjack.DefaultOptions().DelayJitter, "milliseconds")
 	fmt.Fprintf(w, "\t%s, %s\t%s\t(Default: %s)\n", "-ua", "--user-agent", "set user agent", npmjack.DefaultOptions().UserAgent)
 	fmt.Fprintf(w, "\t%s,  %s\t%s\t(Example: %s)\n", "-p", "--proxy", "proxy URL", "127.0.0.1:8080")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %s)\n", "  ", "--registry", "public registry URLs (comma separated)", npmjack.DefaultOptions().Registries[0].URL)
+	fmt.Fprintf(w, "\t%s   %s\t%s\t\n", "  ", "--internal-registry", "internal registry URLs (comma separated)")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Format: %s)\n", "  ", "--registry-file", "file containing registries", "<url> [public|internal] [token]")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %s)\n", "  ", "--ignore", "directories to skip with --path", strings.Join(npmjack.DefaultOptions().IgnoreDirs, ","))
+	fmt.Fprintf(w, "\t%s   %s\t%s\t\n", "  ", "--skip-node-modules", "skip node_modules with --path")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %s)\n", "  ", "--cache", "file to cache registry lookups in", "memory only")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %d %s)\n", "  ", "--cache-ttl", "lifetime of cached registry lookups", npmjack.DefaultOptions().CacheTTL, "hours")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %s)\n", "  ", "--visited-file", "file to remember scanned targets in", "memory only")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %s)\n", "  ", "--node-version", "skip builtins of this Node.js version", "all versions")
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %d)\n", "  ", "--crawl-depth", "levels of HTML page scripts to fetch", npmjack.DefaultOptions().CrawlDepth)
+	fmt.Fprintf(w, "\t%s   %s\t%s\t(Default: %s)\n", "  ", "--crawl-hosts", "other hosts to fetch scripts from", "page host only")
 
 	fmt.Fprintf(w, "\nOUTPUT:\n")
 	fmt.Fprintf(w, "\t%s,  %s\t%s\n", "-o", "--outfile", "output results to given file")
+	fmt.Fprintf(w, "\t%s   %s\t%s\n", "  ", "--json", "output results as a JSON array (to outfile if given)")
+	fmt.Fprintf(w, "\t%s   %s\t%s\n", "  ", "--jsonl", "output results as JSON Lines (to outfile if given)")
+	fmt.Fprintf(w, "\t%s   %s\t%s\n", "  ", "--csv", "output results as CSV (to outfile if given)")
 	fmt.Fprintf(w, "\t%s, %s\t%s\n", "-hc", "--hide-claimed", "hide packages that are claimed")
+	fmt.Fprintf(w, "\t%s   %s\t%s\n", "  ", "--min-confidence", "skip packages detected with lower confidence (0-1)")
 	fmt.Fprintf(w, "\t%s,  %s\t%s\n", "-s", "--silence", "silence everything")
 	fmt.Fprintf(w, "\t%s,  %s\t%s\n", "-v", "--verbose", "verbose output")
 	fmt.Fprintf(w, "\t%s   %s\t%s\n", "  ", "--version", "display version")
@@ -49,6 +66,10 @@ func (c *CLI) parseFlags() {
 	flag.StringVar(&c.TargetURL, "u", "", "")
 	flag.StringVar(&c.Infile, "i", "", "")
 	flag.StringVar(&c.Infile, "infile", "", "")
+	flag.StringVar(&c.Path, "f", "", "")
+	flag.StringVar(&c.Path, "path", "", "")
+	flag.StringVar(&c.IgnoreDirs, "ignore", strings.Join(npmjack.DefaultOptions().IgnoreDirs, ","), "")
+	flag.BoolVar(&c.SkipNodeModules, "skip-node-modules", false, "")
 
 	// CONFIGURATIONS
 	flag.IntVar(&c.Concurrency, "concurrency", npmjack.DefaultOptions().Concurrency, "")
@@ -65,14 +86,27 @@ func (c *CLI) parseFlags() {
 	flag.StringVar(&c.Proxy, "p", "", "")
 	flag.StringVar(&c.ResolversFile, "resolvers", "", "")
 	flag.StringVar(&c.ResolversFile, "r", "", "")
+	flag.StringVar(&c.Registries, "registry", "", "")
+	flag.StringVar(&c.InternalRegistries, "internal-registry", "", "")
+	flag.StringVar(&c.RegistriesFile, "registry-file", "", "")
+	flag.StringVar(&c.CacheFile, "cache", "", "")
+	flag.IntVar(&c.CacheTTL, "cache-ttl", npmjack.DefaultOptions().CacheTTL, "")
+	flag.StringVar(&c.VisitedFile, "visited-file", "", "")
+	flag.StringVar(&c.NodeVersion, "node-version", "", "")
+	flag.IntVar(&c.CrawlDepth, "crawl-depth", npmjack.DefaultOptions().CrawlDepth, "")
+	flag.StringVar(&c.CrawlHosts, "crawl-hosts", "", "")
 
 	// OUTPUT
 	flag.BoolVar(&c.Silence, "s", false, "")
 	flag.BoolVar(&c.Silence, "silence", false, "")
 	flag.StringVar(&c.Outfile, "o", "", "")
 	flag.StringVar(&c.Outfile, "outfile", "", "")
+	flag.BoolVar(&c.JSON, "json", false, "")
+	flag.BoolVar(&c.JSONL, "jsonl", false, "")
+	flag.BoolVar(&c.CSV, "csv", false, "")
 	flag.BoolVar(&c.HideClaimed, "hc", false, "")
 	flag.BoolVar(&c.HideClaimed, "hide-claimed", false, "")
+	flag.Float64Var(&c.MinConfidence, "min-confidence", 0, "")
 	flag.BoolVar(&c.Verbose, "v", false, "")
 	flag.BoolVar(&c.Verbose, "verbose", false, "")
 	flag.BoolVar(&c.Help, "help", false, "")
@@ -9,6 +9,7 @@ require (
 	github.com/gookit/color v1.6.0
 	github.com/miekg/dns v1.1.68
 	github.com/root4loot/goutils v0.0.0-20250924090353-6b134a9999cc
+	golang.org/x/net v0.44.0
 )
 
 require (
@@ -22,7 +23,6 @@ require (
 	golang.org/x/crypto v0.42.0 // indirect
 	golang.org/x/exp v0.0.0-20250106191152-7588d65b2ba8 // indirect
 	golang.org/x/mod v0.28.0 // indirect
-	golang.org/x/net v0.44.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/term v0.35.0 // indirect
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	npmjack "github.com/root4loot/npmjack/pkg/runner"
+)
+
+// resultWriter writes results in a particular format. Each result is written
+// with a single write so concurrent results never interleave
+type resultWriter interface {
+	write(result npmjack.Result) error
+	close() error
+}
+
+// newResultWriter returns a writer for the given format (text, json, jsonl or csv)
+func newResultWriter(w io.Writer, format string) resultWriter {
+	switch format {
+	case "json":
+		return newJSONWriter(w, false)
+	case "jsonl":
+		return newJSONWriter(w, true)
+	case "csv":
+		return newCSVWriter(w)
+	default:
+		return newTextWriter(w)
+	}
+}
+
+// jsonWriter streams results as a JSON array or as JSON Lines
+type jsonWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	lines bool // write JSON Lines instead of a JSON array
+	count int  // number of results written
+}
+
+func newJSONWriter(w io.Writer, lines bool) *jsonWriter {
+	return &jsonWriter{w: w, lines: lines}
+}
+
+// write encodes a single result
+func (j *jsonWriter) write(result npmjack.Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch {
+	case j.lines:
+		data = append(data, '\n')
+	case j.count == 0:
+		data = append([]byte("[\n"), data...)
+	default:
+		data = append([]byte(",\n"), data...)
+	}
+	j.count++
+
+	_, err = j.w.Write(data)
+	return err
+}
+
+// close terminates the JSON array, if any
+func (j *jsonWriter) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.lines {
+		return nil
+	}
+
+	end := "\n]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// csvHeader lists the CSV columns, one row is written per package
+var csvHeader = []string{
+	"request_url", "referrers", "status_code", "resolver", "error", "package", "namespace",
+	"status", "registry_status", "registry", "scope_status", "internal_status", "internal_registry",
+	"scope_registry", "confidence", "methods", "evidence_source", "line", "column", "snippet",
+}
+
+// csvWriter writes one CSV row per package
+type csvWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	header bool // whether the header has been written
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: w}
+}
+
+// write encodes a single result. Results without packages get a single row
+// so failed requests are still recorded
+func (c *csvWriter) write(result npmjack.Result) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if !c.header {
+		w.Write(csvHeader)
+		c.header = true
+	}
+
+	errMsg := ""
+	if result.Error != nil {
+		errMsg = result.Error.Error()
+	}
+	prefix := []string{result.RequestURL, strings.Join(result.Referrers, ";"), strconv.Itoa(result.StatusCode), result.Resolver, errMsg}
+
+	if len(result.Packages) == 0 {
+		w.Write(append(prefix, make([]string, len(csvHeader)-len(prefix))...))
+	}
+
+	for _, pkg := range result.Packages {
+		var evidence npmjack.Evidence
+		if len(pkg.Evidence) > 0 {
+			evidence = pkg.Evidence[0]
+		}
+		line, column := "", ""
+		if evidence.Line > 0 {
+			line, column = strconv.Itoa(evidence.Line), strconv.Itoa(evidence.Column)
+		}
+
+		scopeStatus, internalStatus := "", ""
+		if pkg.IsScoped() {
+			scopeStatus = pkg.ScopeStatus.String()
+		}
+		if pkg.InternalRegistry != "" {
+			internalStatus = pkg.InternalStatus.String()
+		}
+
+		w.Write(append(prefix,
+			pkg.FullName(),
+			pkg.Namespace,
+			pkg.Status.String(),
+			strconv.Itoa(pkg.RegistryStatus),
+			pkg.Registry,
+			scopeStatus,
+			internalStatus,
+			pkg.InternalRegistry,
+			pkg.ScopeRegistry,
+			strconv.FormatFloat(pkg.Confidence, 'f', 2, 64),
+			strings.Join(pkg.Methods(), ";"),
+			evidence.Source,
+			line,
+			column,
+			evidence.Snippet,
+		))
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	_, err := c.w.Write(buf.Bytes())
+	return err
+}
+
+// close writes the header if no results were written
+func (c *csvWriter) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.header {
+		return nil
+	}
+	_, err := io.WriteString(c.w, strings.Join(csvHeader, ",")+"\n")
+	return err
+}
+
+// textWriter writes one "<status> <url> <package> <claimed> <scope> <confidence>
+// <methods> <position>" line per package, or "<status> <url>" for results
+// without packages
+type textWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newTextWriter(w io.Writer) *textWriter {
+	return &textWriter{w: w}
+}
+
+// write encodes a single result
+func (t *textWriter) write(result npmjack.Result) error {
+	var buf strings.Builder
+
+	prefix := strconv.Itoa(result.StatusCode) + " " + result.RequestURL
+	for _, pkg := range result.Packages {
+		buf.WriteString(prefix + " " + pkg.FullName() + " " + claimLabel(pkg.Status) + " " + scopeLabel(pkg) + " " + strconv.FormatFloat(pkg.Confidence, 'f', 2, 64) + " " + methodLabel(pkg) + " " + positionLabel(result, pkg) + "\n")
+	}
+	if len(result.Packages) == 0 {
+		buf.WriteString(prefix + "\n")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err := io.WriteString(t.w, buf.String())
+	return err
+}
+
+func (t *textWriter) close() error {
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	npmjack "github.com/root4loot/npmjack/pkg/runner"
+)
+
+var testResults = []npmjack.Result{
+	{
+		RequestURL: "https://target.com/package.json",
+		StatusCode: 200,
+		Packages: []npmjack.Package{
+			{Name: "express", Status: npmjack.StatusClaimed, Evidence: []npmjack.Evidence{
+				{Extractor: "json", Method: "package-json", Source: "https://target.com/package.json", Offset: 22, Line: 2, Column: 20, Snippet: `"dependencies": {"express": "^4.18.0"}`},
+			}},
+			{Name: "private-pkg", Namespace: "@company", Status: npmjack.StatusUnclaimed, ScopeStatus: npmjack.StatusUnclaimed},
+		},
+	},
+	{RequestURL: "https://target.com/missing.js", Error: errors.New("connection refused")},
+}
+
+func writeConcurrently(t *testing.T, w resultWriter, n int) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		for _, result := range testResults {
+			wg.Add(1)
+			go func(result npmjack.Result) {
+				defer wg.Done()
+				if err := w.write(result); err != nil {
+					t.Errorf("write: %v", err)
+				}
+			}(result)
+		}
+	}
+	wg.Wait()
+
+	if err := w.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writeConcurrently(t, newResultWriter(&buf, "json"), 10)
+
+	var results []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil {
+		t.Fatalf("invalid JSON array: %v\n%s", err, buf.String())
+	}
+	if len(results) != 20 {
+		t.Errorf("expected 20 results, got %d", len(results))
+	}
+
+	buf.Reset()
+	newResultWriter(&buf, "json").close()
+	if buf.String() != "[]\n" {
+		t.Errorf("expected empty array, got %q", buf.String())
+	}
+}
+
+func TestJSONLinesWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writeConcurrently(t, newResultWriter(&buf, "jsonl"), 10)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 20 {
+		t.Fatalf("expected 20 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid(line) {
+			t.Errorf("invalid JSON line: %s", line)
+		}
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writeConcurrently(t, newResultWriter(&buf, "csv"), 10)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+
+	// header + 10 * (2 package rows + 1 error row)
+	if len(rows) != 31 {
+		t.Fatalf("expected 31 rows, got %d", len(rows))
+	}
+	if rows[0][0] != "request_url" {
+		t.Errorf("expected header row first, got %v", rows[0])
+	}
+
+	for _, row := range rows[1:] {
+		if row[5] == "@company/private-pkg" && (row[7] != "unclaimed" || row[10] != "unclaimed") {
+			t.Errorf("unexpected scoped package row: %v", row)
+		}
+		if row[5] == "express" && (row[15] != "package-json" || row[17] != "2" || row[18] != "20") {
+			t.Errorf("unexpected evidence columns: %v", row)
+		}
+		if row[0] == "https://target.com/missing.js" && row[4] != "connection refused" {
+			t.Errorf("unexpected error row: %v", row)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NodeVersion is a Node.js release, compared by major and minor version
+type NodeVersion struct {
+	Major int
+	Minor int
+}
+
+// builtinModule is a Node.js builtin module and the release that added it
+type builtinModule struct {
+	since      NodeVersion
+	prefixOnly bool // only importable with the node: prefix, e.g. node:test
+}
+
+// builtinModules lists the modules of require('module').builtinModules,
+// including subpath modules and the node:-only modules
+var builtinModules = map[string]builtinModule{
+	"assert":              {},
+	"assert/strict":       {since: NodeVersion{15, 0}},
+	"async_hooks":         {since: NodeVersion{8, 1}},
+	"buffer":              {},
+	"child_process":       {},
+	"cluster":             {},
+	"console":             {},
+	"constants":           {},
+	"crypto":              {},
+	"dgram":               {},
+	"diagnostics_channel": {since: NodeVersion{15, 1}},
+	"dns":                 {},
+	"dns/promises":        {since: NodeVersion{15, 0}},
+	"domain":              {},
+	"events":              {},
+	"fs":                  {},
+	"fs/promises":         {since: NodeVersion{14, 0}},
+	"http":                {},
+	"http2":               {since: NodeVersion{8, 4}},
+	"https":               {},
+	"inspector":           {since: NodeVersion{8, 0}},
+	"inspector/promises":  {since: NodeVersion{19, 0}},
+	"module":              {},
+	"net":                 {},
+	"os":                  {},
+	"path":                {},
+	"path/posix":          {since: NodeVersion{15, 3}},
+	"path/win32":          {since: NodeVersion{15, 3}},
+	"perf_hooks":          {since: NodeVersion{8, 5}},
+	"process":             {},
+	"punycode":            {},
+	"querystring":         {},
+	"readline":            {},
+	"readline/promises":   {since: NodeVersion{17, 0}},
+	"repl":                {},
+	"stream":              {},
+	"stream/consumers":    {since: NodeVersion{16, 7}},
+	"stream/promises":     {since: NodeVersion{15, 0}},
+	"stream/web":          {since: NodeVersion{16, 5}},
+	"string_decoder":      {},
+	"sys":                 {},
+	"timers":              {},
+	"timers/promises":     {since: NodeVersion{15, 0}},
+	"tls":                 {},
+	"trace_events":        {since: NodeVersion{10, 0}},
+	"tty":                 {},
+	"url":                 {},
+	"util":                {},
+	"util/types":          {since: NodeVersion{15, 3}},
+	"v8":                  {since: NodeVersion{1, 0}},
+	"vm":                  {},
+	"wasi":                {since: NodeVersion{13, 3}},
+	"worker_threads":      {since: NodeVersion{10, 5}},
+	"zlib":                {},
+
+	"_http_agent":         {},
+	"_http_client":        {},
+	"_http_common":        {},
+	"_http_incoming":      {},
+	"_http_outgoing":      {},
+	"_http_server":        {},
+	"_stream_duplex":      {},
+	"_stream_passthrough": {},
+	"_stream_readable":    {},
+	"_stream_transform":   {},
+	"_stream_wrap":        {},
+	"_stream_writable":    {},
+	"_tls_common":         {},
+	"_tls_wrap":           {},
+
+	"sea":            {since: NodeVersion{20, 12}, prefixOnly: true},
+	"sqlite":         {since: NodeVersion{22, 5}, prefixOnly: true},
+	"test":           {since: NodeVersion{18, 0}, prefixOnly: true},
+	"test/reporters": {since: NodeVersion{19, 9}, prefixOnly: true},
+}
+
+// ParseNodeVersion parses a Node.js version such as "18", "20.11" or
+// "v22.5.1". Patch versions are ignored
+func ParseNodeVersion(s string) (NodeVersion, error) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".", 3)
+
+	var v NodeVersion
+	var err error
+	if v.Major, err = strconv.Atoi(parts[0]); err != nil || v.Major < 0 {
+		return NodeVersion{}, fmt.Errorf("invalid Node.js version %q", s)
+	}
+	if len(parts) > 1 {
+		if v.Minor, err = strconv.Atoi(parts[1]); err != nil || v.Minor < 0 {
+			return NodeVersion{}, fmt.Errorf("invalid Node.js version %q", s)
+		}
+	}
+	return v, nil
+}
+
+// IsZero reports whether v is the zero version, which stands for any release
+func (v NodeVersion) IsZero() bool {
+	return v == NodeVersion{}
+}
+
+func (v NodeVersion) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor)
+}
+
+func (v NodeVersion) less(o NodeVersion) bool {
+	return v.Major < o.Major || v.Major == o.Major && v.Minor < o.Minor
+}
+
+// IsBuiltinModule reports whether name, with or without the node: prefix, is
+// a builtin module of the given Node.js release. For the zero version every
+// known builtin counts, so only names that are builtins in no release are
+// left to be looked up on the registries
+func IsBuiltinModule(name string, version NodeVersion) bool {
+	bare := strings.TrimPrefix(name, "node:")
+
+	module, ok := builtinModules[bare]
+	if !ok || module.prefixOnly && bare == name {
+		return false
+	}
+	return version.IsZero() || !version.less(module.since)
+}
+
+func (r *Runner) isBuiltinModule(name string) bool {
+	return IsBuiltinModule(name, r.Options.NodeVersion)
+}
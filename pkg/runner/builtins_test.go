@@ -0,0 +1,74 @@
+package runner
+
+import "testing"
+
+func TestIsBuiltinModule(t *testing.T) {
+	tests := []struct {
+		name    string
+		version NodeVersion
+		builtin bool
+	}{
+		{"fs", NodeVersion{}, true},
+		{"node:fs", NodeVersion{}, true},
+		{"fs/promises", NodeVersion{}, true},
+		{"worker_threads", NodeVersion{}, true},
+		{"string_decoder", NodeVersion{}, true},
+		{"node:test", NodeVersion{}, true},
+		{"node:sqlite", NodeVersion{}, true},
+		{"test", NodeVersion{}, false},
+		{"sqlite", NodeVersion{}, false},
+		{"lodash", NodeVersion{}, false},
+		{"node:lodash", NodeVersion{}, false},
+		{"diagnostics_channel", NodeVersion{14, 17}, false},
+		{"diagnostics_channel", NodeVersion{16, 0}, true},
+		{"worker_threads", NodeVersion{10, 5}, true},
+		{"worker_threads", NodeVersion{10, 4}, false},
+		{"node:test", NodeVersion{16, 0}, false},
+		{"fs", NodeVersion{0, 10}, true},
+	}
+
+	for _, tt := range tests {
+		if got := IsBuiltinModule(tt.name, tt.version); got != tt.builtin {
+			t.Errorf("%q on %v: expected %v, got %v", tt.name, tt.version, tt.builtin, got)
+		}
+	}
+}
+
+func TestParseNodeVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		version NodeVersion
+		valid   bool
+	}{
+		{"18", NodeVersion{18, 0}, true},
+		{"20.11", NodeVersion{20, 11}, true},
+		{"v22.5.1", NodeVersion{22, 5}, true},
+		{"lts", NodeVersion{}, false},
+		{"20.x", NodeVersion{}, false},
+		{"", NodeVersion{}, false},
+	}
+
+	for _, tt := range tests {
+		version, err := ParseNodeVersion(tt.input)
+		if tt.valid != (err == nil) || version != tt.version {
+			t.Errorf("%q: expected %v valid=%v, got %v %v", tt.input, tt.version, tt.valid, version, err)
+		}
+	}
+}
+
+func TestNodeVersionBuiltins(t *testing.T) {
+	content := `import dc from "diagnostics_channel";
+import { Worker } from "worker_threads";`
+
+	runner := NewRunner()
+	if packages := runner.extractPackages("app.js", "", []byte(content)); len(packages) != 0 {
+		t.Errorf("expected builtins to be skipped, got %v", packages)
+	}
+
+	// diagnostics_channel was added in Node.js 15.1, so on 14 it's a package
+	runner.Options.NodeVersion = NodeVersion{14, 0}
+	packages := runner.extractPackages("app.js", "", []byte(content))
+	if _, ok := findPackage(packages, "diagnostics_channel"); !ok || len(packages) != 1 {
+		t.Errorf("expected only diagnostics_channel on Node.js 14, got %v", packages)
+	}
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/root4loot/goutils/log"
+)
+
+// registryCache caches registry lookups and deduplicates in-flight lookups
+// so that every goroutine started by Run shares a single request per package
+type registryCache struct {
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*lookupCall
+	ttl      time.Duration
+}
+
+type cacheEntry struct {
+	Status     ClaimStatus `json:"status"`
+	StatusCode int         `json:"status_code"`
+	Checked    time.Time   `json:"checked"`
+}
+
+type lookupCall struct {
+	done   chan struct{}
+	status ClaimStatus
+	code   int
+	err    error
+}
+
+func newRegistryCache(ttl time.Duration) *registryCache {
+	return &registryCache{
+		entries:  make(map[string]cacheEntry),
+		inflight: make(map[string]*lookupCall),
+		ttl:      ttl,
+	}
+}
+
+// lookup returns the cached status for key, or calls fn once no matter how
+// many goroutines ask for the same key concurrently
+func (c *registryCache) lookup(key string, fn func() (ClaimStatus, int, error)) (ClaimStatus, int, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && !c.expired(entry) {
+		c.mu.Unlock()
+		log.Debugf("Registry cache hit for %s", key)
+		return entry.Status, entry.StatusCode, nil
+	}
+
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.status, call.code, call.err
+	}
+
+	call := &lookupCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.status, call.code, call.err = fn()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	// only definitive answers are cached so failed lookups are retried
+	if call.status == StatusClaimed || call.status == StatusUnclaimed {
+		c.entries[key] = cacheEntry{Status: call.status, StatusCode: call.code, Checked: time.Now()}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.status, call.code, call.err
+}
+
+func (c *registryCache) expired(entry cacheEntry) bool {
+	return c.ttl > 0 && time.Since(entry.Checked) > c.ttl
+}
+
+// load reads cache entries from the given file, skipping expired ones
+func (c *registryCache) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var entries map[string]cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range entries {
+		if !c.expired(entry) {
+			c.entries[key] = entry
+		}
+	}
+	log.Debugf("Loaded %d registry cache entries from %s", len(c.entries), path)
+	return nil
+}
+
+// save writes unexpired cache entries to the given file
+func (c *registryCache) save(path string) error {
+	c.mu.Lock()
+	entries := make(map[string]cacheEntry, len(c.entries))
+	for key, entry := range c.entries {
+		if !c.expired(entry) {
+			entries[key] = entry
+		}
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
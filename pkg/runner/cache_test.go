@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistryCacheDeduplicatesLookups(t *testing.T) {
+	cache := newRegistryCache(time.Hour)
+
+	var calls int32
+	release := make(chan struct{})
+	lookup := func() (ClaimStatus, int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return StatusClaimed, 200, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status, _, _ := cache.lookup("react", lookup); status != StatusClaimed {
+				t.Errorf("expected %v, got %v", StatusClaimed, status)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	cache.lookup("react", lookup)
+	if calls != 1 {
+		t.Errorf("expected 1 registry lookup, got %d", calls)
+	}
+}
+
+func TestRegistryCacheSkipsFailedLookups(t *testing.T) {
+	cache := newRegistryCache(time.Hour)
+
+	var calls int
+	lookup := func() (ClaimStatus, int, error) {
+		calls++
+		return StatusUnknown, 503, nil
+	}
+
+	cache.lookup("react", lookup)
+	cache.lookup("react", lookup)
+	if calls != 2 {
+		t.Errorf("expected failed lookups to be retried, got %d lookups", calls)
+	}
+}
+
+func TestRegistryCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	cache := newRegistryCache(time.Hour)
+	cache.lookup("react", func() (ClaimStatus, int, error) { return StatusClaimed, 200, nil })
+	cache.lookup("unclaimed-package-123", func() (ClaimStatus, int, error) { return StatusUnclaimed, 404, nil })
+	if err := cache.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded := newRegistryCache(time.Hour)
+	if err := loaded.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	status, code, _ := loaded.lookup("unclaimed-package-123", func() (ClaimStatus, int, error) {
+		t.Error("expected cached entry to be used")
+		return StatusUnknown, 0, nil
+	})
+	if status != StatusUnclaimed || code != 404 {
+		t.Errorf("expected unclaimed/404, got %v/%d", status, code)
+	}
+
+	expired := newRegistryCache(time.Nanosecond)
+	if err := expired.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(expired.entries) != 0 {
+		t.Errorf("expected expired entries to be dropped, got %d", len(expired.entries))
+	}
+}
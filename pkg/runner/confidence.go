@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"math"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// methodConfidence is the confidence in a single detection by each method.
+// Lockfiles, manifests and real import statements name packages outright,
+// while the loose bundle patterns mostly match ordinary strings
+var methodConfidence = map[string]float64{
+	"package-json":           0.95,
+	"package-json-name":      0.95,
+	"package-json-override":  0.9,
+	"package-json-workspace": 0.5,
+	"publish-config":         0.95,
+	"vite-manifest":          0.9,
+	"esbuild-metafile":       0.9,
+	"package-lock":           0.95,
+	"yarn-lock":              0.95,
+	"yarn-lock-dependency":   0.9,
+	"pnpm-lock":              0.95,
+	"pnpm-lock-dependency":   0.9,
+	"yarnrc-scope":           0.95,
+	"yarnrc-extension":       0.8,
+	"npmrc-scope":            0.95,
+	"npmrc-auth":             0.95,
+	"import-from":            0.9,
+	"export-from":            0.9,
+	"dynamic-import":         0.9,
+	"require-resolve":        0.9,
+	"sourcemap-node-modules": 0.9,
+	"require-import":         0.85,
+	"sourcemap-file":         0.85,
+	"cdn-url":                0.85,
+	"script-src":             0.85,
+	"import-map":             0.8,
+	"cicd-npm-install":       0.8,
+	"cicd-yarn-add":          0.8,
+	"cicd-pnpm-add":          0.75,
+	"amd-define":             0.75,
+	"amd-require":            0.75,
+	"cicd-npx":               0.7,
+	"doc-npm-install":        0.7,
+	"doc-yarn-add":           0.7,
+	"doc-pnpm-add":           0.7,
+	"webpack-chunk":          0.7,
+	"config-loader":          0.7,
+	"webpack-external":       0.65,
+	"config-array":           0.6,
+	"npm-install":            0.6,
+	"yarn-add":               0.6,
+	"umd-factory":            0.6,
+	"parcel-require":         0.6,
+	"rollup-require":         0.6,
+	"doc-npx":                0.6,
+	"requirejs-paths":        0.5,
+	"doc-yarn-create":        0.5,
+	"doc-npm-create":         0.5,
+	"doc-json-example":       0.5,
+	"doc-inline-code":        0.3,
+	"sourcemap-path":         0.3,
+	"umd-global":             0.3,
+	"config-string":          0.25,
+	"minified-call":          0.2,
+	"bundle-comment":         0.15,
+	"global-assign":          0.15,
+}
+
+const (
+	defaultConfidence = 0.5  // confidence in methods missing from methodConfidence
+	nonCodeFactor     = 0.75 // applied to JavaScript patterns matched outside code
+)
+
+// javaScriptMethods are the methods that match JavaScript syntax, which is
+// weaker evidence in files that aren't code
+var javaScriptMethods = map[string]bool{
+	"import-from": true, "export-from": true, "dynamic-import": true, "require-resolve": true, "require-import": true,
+	"amd-define": true, "amd-require": true, "requirejs-paths": true, "umd-factory": true,
+	"umd-global": true, "global-assign": true, "minified-call": true, "parcel-require": true,
+	"rollup-require": true, "bundle-comment": true, "webpack-chunk": true, "webpack-external": true,
+}
+
+// nonCodeExtensions are extensions of files that aren't JavaScript, HTML or
+// source maps. Files without an extension are assumed to be code since
+// scripts are often served from extensionless URLs
+var nonCodeExtensions = map[string]bool{
+	".md": true, ".rst": true, ".txt": true, ".json": true, ".lock": true, ".yml": true,
+	".yaml": true, ".xml": true, ".csv": true, ".css": true, ".sh": true, ".bash": true,
+}
+
+var hashLikeRegex = regexp.MustCompile(`^[0-9a-f]{8,}$`)
+
+// scoreEvidence sets the confidence of evidence that doesn't have one yet
+func scoreEvidence(packages []Package) {
+	for i := range packages {
+		for j := range packages[i].Evidence {
+			e := &packages[i].Evidence[j]
+			if e.Confidence != 0 {
+				continue
+			}
+
+			confidence, ok := methodConfidence[e.Method]
+			if !ok {
+				confidence = defaultConfidence
+			}
+			if javaScriptMethods[e.Method] && !isCodeFile(e.Source) {
+				confidence *= nonCodeFactor
+			}
+			e.Confidence = round2(confidence)
+		}
+	}
+}
+
+// scorePackage sets the confidence of the package from its evidence. Methods
+// that agree strengthen each other, while repeated matches of a single
+// method don't, and the result is weakened for names that don't look like
+// packages
+func scorePackage(pkg *Package) {
+	best := make(map[string]float64) // highest confidence per method
+	for _, e := range pkg.Evidence {
+		best[e.Method] = math.Max(best[e.Method], e.Confidence)
+	}
+
+	doubt := 1.0
+	for _, confidence := range best {
+		doubt *= 1 - confidence
+	}
+
+	pkg.Confidence = round2((1 - doubt) * nameShapeFactor(*pkg))
+}
+
+// nameShapeFactor returns how much a name looks like a real package name, as
+// a factor between 0 and 1
+func nameShapeFactor(pkg Package) float64 {
+	if pkg.IsScoped() || pkg.IsRegistryOnly() {
+		return 1
+	}
+
+	name := pkg.Name
+	switch {
+	case hashLikeRegex.MatchString(name) && strings.ContainsAny(name, "0123456789"):
+		return 0.3 // chunk or content hashes
+	case strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") || strings.Contains(name, "--"):
+		return 0.5
+	case len(name) <= 3:
+		return 0.7
+	}
+	return 1
+}
+
+// isCodeFile reports whether source looks like JavaScript, HTML, a source
+// map or anything else that may hold code
+func isCodeFile(source string) bool {
+	if u := strings.IndexAny(source, "?#"); u != -1 && strings.Contains(source, "://") {
+		source = source[:u]
+	}
+	return !nonCodeExtensions[strings.ToLower(path.Ext(source))]
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
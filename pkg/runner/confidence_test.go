@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestScorePackage(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkg      string
+		evidence []Evidence
+		min, max float64
+	}{
+		{
+			name:     "lockfile entry",
+			pkg:      "left-pad",
+			evidence: []Evidence{{Method: "yarn-lock", Source: "yarn.lock"}},
+			min:      0.95, max: 0.95,
+		},
+		{
+			name:     "bundle comment",
+			pkg:      "widget-core",
+			evidence: []Evidence{{Method: "bundle-comment", Source: "app.js"}},
+			min:      0.15, max: 0.15,
+		},
+		{
+			name: "agreeing methods",
+			pkg:  "widget-core",
+			evidence: []Evidence{
+				{Method: "bundle-comment", Source: "app.js"},
+				{Method: "minified-call", Source: "app.js"},
+			},
+			min: 0.31, max: 0.33,
+		},
+		{
+			name: "repeated method",
+			pkg:  "widget-core",
+			evidence: []Evidence{
+				{Method: "bundle-comment", Source: "app.js", Offset: 1},
+				{Method: "bundle-comment", Source: "app.js", Offset: 2},
+			},
+			min: 0.15, max: 0.15,
+		},
+		{
+			name:     "import outside code",
+			pkg:      "widget-core",
+			evidence: []Evidence{{Method: "import-from", Source: "https://target.com/notes.txt?v=1"}},
+			min:      0.67, max: 0.68,
+		},
+		{
+			name:     "hash-like name",
+			pkg:      "3f2a9c1b7d",
+			evidence: []Evidence{{Method: "import-from", Source: "app.js"}},
+			min:      0.27, max: 0.27,
+		},
+		{
+			name:     "unknown method",
+			pkg:      "widget-core",
+			evidence: []Evidence{{Method: "in-house", Source: "app.js"}},
+			min:      defaultConfidence, max: defaultConfidence,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, err := ParsePackageName(tt.pkg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg.Evidence = tt.evidence
+
+			packages := []Package{pkg}
+			scoreEvidence(packages)
+			scorePackage(&packages[0])
+
+			if got := packages[0].Confidence; got < tt.min || got > tt.max {
+				t.Errorf("expected confidence in [%.2f, %.2f], got %.2f", tt.min, tt.max, got)
+			}
+		})
+	}
+}
+
+func TestMinConfidence(t *testing.T) {
+	runner := newStubRegistry(t, map[string]int{"/react": http.StatusOK})
+	runner.Options.MinConfidence = 0.5
+
+	body := []byte("import React from 'react';\nwindow.someglobal = {};\n")
+	packages := runner.checkPackages(context.Background(), "app.js", "", body)
+
+	if _, ok := findPackage(packages, "react"); !ok {
+		t.Errorf("expected react to be kept, got %v", packages)
+	}
+	if _, ok := findPackage(packages, "someglobal"); ok {
+		t.Errorf("expected low confidence package to be dropped")
+	}
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// link is a URL found in a response that is to be scanned next
+type link struct {
+	url   string
+	crawl bool // found by crawling, which is bounded by the crawl depth and hosts
+}
+
+// importMap is the JSON of a <script type="importmap"> element
+type importMap struct {
+	Imports map[string]string            `json:"imports"`
+	Scopes  map[string]map[string]string `json:"scopes"`
+}
+
+// htmlExtensions are the extensions of HTML pages served without a media type
+var htmlExtensions = []string{".html", ".htm"}
+
+// isHTMLPage reports whether a response is an HTML page, judging by its
+// media type or, if it is missing, the URL
+func (r *Runner) isHTMLPage(url, contentType string) bool {
+	if contentType != "" {
+		return contentType == "text/html" || contentType == "application/xhtml+xml"
+	}
+
+	lowerURL := strings.ToLower(trimURLParams(url))
+	for _, ext := range htmlExtensions {
+		if strings.HasSuffix(lowerURL, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlAssetLinks returns the scripts an HTML page loads: <script src>,
+// <link rel="modulepreload"> and the modules of <script type="importmap">,
+// resolved against the page URL or its <base href>
+func htmlAssetLinks(body []byte, base *url.URL) []link {
+	var links []link
+	seen := make(map[string]bool)
+
+	add := func(ref string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasSuffix(ref, "/") {
+			return
+		}
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			return
+		}
+		if base != nil {
+			refURL = base.ResolveReference(refURL)
+		}
+		if refURL.Scheme != "http" && refURL.Scheme != "https" {
+			return
+		}
+		refURL.Fragment = ""
+		if u := refURL.String(); !seen[u] {
+			seen[u] = true
+			links = append(links, link{url: u, crawl: true})
+		}
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	baseSet := false
+	inImportMap := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+
+		case html.TextToken:
+			if !inImportMap {
+				continue
+			}
+			var imports importMap
+			if err := json.Unmarshal(tokenizer.Text(), &imports); err != nil {
+				continue
+			}
+			for _, module := range imports.Imports {
+				add(module)
+			}
+			for _, scope := range imports.Scopes {
+				for _, module := range scope {
+					add(module)
+				}
+			}
+
+		case html.EndTagToken:
+			inImportMap = false
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attrs := make(map[string]string)
+			for _, attr := range token.Attr {
+				attrs[attr.Key] = attr.Val
+			}
+
+			switch token.Data {
+			case "base":
+				if href, ok := attrs["href"]; ok && !baseSet && base != nil {
+					if hrefURL, err := url.Parse(strings.TrimSpace(href)); err == nil {
+						base = base.ResolveReference(hrefURL)
+						baseSet = true
+					}
+				}
+			case "script":
+				if strings.EqualFold(strings.TrimSpace(attrs["type"]), "importmap") {
+					inImportMap = token.Type == html.StartTagToken
+				} else if src, ok := attrs["src"]; ok {
+					add(src)
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if rel == "modulepreload" {
+						add(attrs["href"])
+					}
+				}
+			}
+		}
+	}
+}
+
+// inCrawlScope reports whether a crawled URL may be fetched: it must be on
+// the host of the page it was found in, or on one of Options.CrawlHosts
+func (r *Runner) inCrawlScope(target, referrer string) bool {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if referrerURL, err := url.Parse(referrer); err == nil && strings.EqualFold(targetURL.Host, referrerURL.Host) {
+		return true
+	}
+
+	for _, pattern := range r.Options.CrawlHosts {
+		if matchHost(pattern, targetURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether the host of u matches a crawl host pattern:
+// a host name, a host:port, or *.example.com for any subdomain of
+// example.com
+func matchHost(pattern string, u *url.URL) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(pattern, ":") {
+		host = strings.ToLower(u.Host)
+	}
+
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestHTMLAssetLinks(t *testing.T) {
+	page := `<!doctype html>
+<html><head>
+<base href="/app/">
+<script type="importmap">{"imports": {"vue": "/vendor/vue.esm.js", "lib/": "/vendor/lib/"}, "scopes": {"/admin/": {"chart": "https://cdn.example.net/chart.js"}}}</script>
+<link rel="modulepreload" href="chunks/shared.js">
+<link rel="stylesheet" href="style.css">
+<script src="main.js#x"></script>
+<script src="main.js"></script>
+<script src="data:text/javascript,alert(1)"></script>
+<script>import("./inline.js")</script>
+</head></html>`
+
+	base, _ := url.Parse("https://example.com/index.html")
+	var urls []string
+	for _, l := range htmlAssetLinks([]byte(page), base) {
+		urls = append(urls, l.url)
+	}
+
+	expected := []string{
+		"https://example.com/vendor/vue.esm.js",
+		"https://cdn.example.net/chart.js",
+		"https://example.com/app/chunks/shared.js",
+		"https://example.com/app/main.js",
+	}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("expected %v, got %v", expected, urls)
+	}
+}
+
+func TestInCrawlScope(t *testing.T) {
+	runner := NewRunner()
+	runner.Options.CrawlHosts = []string{"*.example.net", "static.example.org:8443"}
+
+	tests := []struct {
+		target string
+		scope  bool
+	}{
+		{"https://example.com/app.js", true},
+		{"https://EXAMPLE.com/app.js", true},
+		{"https://cdn.example.net/app.js", true},
+		{"https://example.net/app.js", false},
+		{"https://static.example.org:8443/app.js", true},
+		{"https://static.example.org/app.js", false},
+		{"https://evil.example/app.js", false},
+	}
+
+	for _, tt := range tests {
+		if scope := runner.inCrawlScope(tt.target, "https://example.com/index.html"); scope != tt.scope {
+			t.Errorf("%s: expected %v, got %v", tt.target, tt.scope, scope)
+		}
+	}
+}
+
+func TestRunCrawlsHTMLPages(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("out of scope host requested: %s", req.URL)
+	}))
+	t.Cleanup(other.Close)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprintf(w, `<script src="/static/app.js"></script><script src="%s/tracker.js"></script>`, other.URL)
+		case "/static/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			// a crawled script's source map on another host is out of scope too
+			fmt.Fprintf(w, "import \"company-crawled-internals\";\n//# sourceMappingURL=%s/app.js.map", other.URL)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	for _, depth := range []int{0, 1} {
+		runner := newStubRegistry(t, nil)
+		runner.Options.CrawlDepth = depth
+		results := collectResults(runner)
+
+		runner.RunContext(context.Background(), server.URL+"/")
+		runner.Close()
+
+		scanned := make(map[string]Result)
+		for _, result := range <-results {
+			scanned[result.RequestURL] = result
+		}
+
+		script, ok := scanned[server.URL+"/static/app.js"]
+		if ok != (depth > 0) {
+			t.Errorf("depth %d: expected script scanned %v, got %v", depth, depth > 0, scanned)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(script.Referrers, []string{server.URL}) {
+			t.Errorf("depth %d: expected referrer %s, got %v", depth, server.URL, script.Referrers)
+		}
+		if _, found := findPackage(script.Packages, "company-crawled-internals"); !found {
+			t.Errorf("depth %d: expected company-crawled-internals, got %v", depth, script.Packages)
+		}
+	}
+}
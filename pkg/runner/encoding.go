@@ -0,0 +1,85 @@
+package runner
+
+import "encoding/json"
+
+type packageJSON struct {
+	Name             string       `json:"name"`
+	Namespace        string       `json:"namespace,omitempty"`
+	FullName         string       `json:"full_name"`
+	Status           ClaimStatus  `json:"status"`
+	RegistryStatus   int          `json:"registry_status,omitempty"`
+	Registry         string       `json:"registry,omitempty"`
+	RegistryError    string       `json:"registry_error,omitempty"`
+	ScopeStatus      *ClaimStatus `json:"scope_status,omitempty"`
+	InternalStatus   *ClaimStatus `json:"internal_status,omitempty"`
+	InternalRegistry string       `json:"internal_registry,omitempty"`
+	ScopeRegistry    string       `json:"scope_registry,omitempty"`
+	PrivateScope     bool         `json:"private_scope,omitempty"`
+	PrivateRegistry  bool         `json:"private_registry,omitempty"`
+	Confidence       float64      `json:"confidence"`
+	Evidence         []Evidence   `json:"evidence,omitempty"`
+}
+
+type resultJSON struct {
+	RequestURL string    `json:"request_url"`
+	Referrers  []string  `json:"referrers,omitempty"`
+	StatusCode int       `json:"status_code"`
+	Resolver   string    `json:"resolver,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Packages   []Package `json:"packages"`
+}
+
+// MarshalJSON encodes the package with its full name, claim statuses as
+// strings and the registry error as a message. The scope and internal
+// statuses are only included when they were checked
+func (p Package) MarshalJSON() ([]byte, error) {
+	out := packageJSON{
+		Name:             p.Name,
+		Namespace:        p.Namespace,
+		FullName:         p.FullName(),
+		Status:           p.Status,
+		RegistryStatus:   p.RegistryStatus,
+		Registry:         p.Registry,
+		RegistryError:    errorString(p.RegistryError),
+		InternalRegistry: p.InternalRegistry,
+		ScopeRegistry:    p.ScopeRegistry,
+		PrivateScope:     p.IsScopeOnly(),
+		PrivateRegistry:  p.IsRegistryOnly(),
+		Confidence:       p.Confidence,
+		Evidence:         p.Evidence,
+	}
+
+	if p.IsScoped() {
+		out.ScopeStatus = &p.ScopeStatus
+	}
+	if p.InternalRegistry != "" {
+		out.InternalStatus = &p.InternalStatus
+	}
+
+	return json.Marshal(out)
+}
+
+// MarshalJSON encodes the result with the error as a message
+func (r Result) MarshalJSON() ([]byte, error) {
+	out := resultJSON{
+		RequestURL: r.RequestURL,
+		Referrers:  r.Referrers,
+		StatusCode: r.StatusCode,
+		Resolver:   r.Resolver,
+		Error:      errorString(r.Error),
+		Packages:   r.Packages,
+	}
+
+	if out.Packages == nil {
+		out.Packages = []Package{}
+	}
+
+	return json.Marshal(out)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
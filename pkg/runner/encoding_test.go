@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResultJSON(t *testing.T) {
+	result := Result{
+		RequestURL: "https://target.com/package.json",
+		StatusCode: 200,
+		Resolver:   "system",
+		Packages: []Package{
+			{Name: "express", Status: StatusClaimed, RegistryStatus: 200, Registry: "https://registry.npmjs.com"},
+			{Name: "private-pkg", Namespace: "@company", Status: StatusUnclaimed, ScopeStatus: StatusUnclaimed},
+			{Name: "flaky", Status: StatusUnknown, RegistryError: errors.New("timeout")},
+		},
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var decoded struct {
+		RequestURL string `json:"request_url"`
+		Packages   []map[string]interface{}
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if decoded.RequestURL != result.RequestURL || len(decoded.Packages) != 3 {
+		t.Fatalf("unexpected result: %s", data)
+	}
+
+	express, private, flaky := decoded.Packages[0], decoded.Packages[1], decoded.Packages[2]
+	if express["status"] != "claimed" || express["scope_status"] != nil {
+		t.Errorf("unexpected express encoding: %v", express)
+	}
+	if private["full_name"] != "@company/private-pkg" || private["scope_status"] != "unclaimed" {
+		t.Errorf("unexpected scoped package encoding: %v", private)
+	}
+	if flaky["status"] != "unknown" || flaky["registry_error"] != "timeout" {
+		t.Errorf("unexpected failed lookup encoding: %v", flaky)
+	}
+
+	empty, _ := json.Marshal(Result{RequestURL: "https://target.com/", Error: errors.New("connection refused")})
+	if !strings.Contains(string(empty), `"packages":[]`) || !strings.Contains(string(empty), `"error":"connection refused"`) {
+		t.Errorf("unexpected empty result encoding: %s", empty)
+	}
+}
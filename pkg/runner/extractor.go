@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/root4loot/goutils/log"
+)
+
+// Extractor finds npm packages in a file. Extractors are called for every
+// scanned URL and file and decide for themselves whether the file is one they
+// understand, returning nil otherwise. Extract may be called concurrently
+type Extractor interface {
+	// Name identifies the extractor, e.g. "javascript" or "yarn-berry"
+	Name() string
+	// Extract returns the packages found in body. filename is the URL or path
+	// the body was read from and contentType its media type, or empty if unknown
+	Extract(filename, contentType string, body []byte) []Package
+}
+
+// Names of the built-in extractors
+const (
+	ExtractorJSON       = "json"
+	ExtractorPnpm       = "pnpm"
+	ExtractorYarnrc     = "yarnrc"
+	ExtractorNpmrc      = "npmrc"
+	ExtractorManifest   = "manifest"
+	ExtractorConfig     = "config"
+	ExtractorCICD       = "cicd"
+	ExtractorDocs       = "docs"
+	ExtractorSourceMap  = "sourcemap"
+	ExtractorJavaScript = "javascript"
+)
+
+// builtinExtractor adapts one of the runner's extraction methods to Extractor
+type builtinExtractor struct {
+	name    string
+	match   func(filename, contentType string) bool
+	extract func(content string) []Package
+}
+
+func (e builtinExtractor) Name() string {
+	return e.name
+}
+
+func (e builtinExtractor) Extract(filename, contentType string, body []byte) []Package {
+	if e.match != nil && !e.match(filename, contentType) {
+		return nil
+	}
+	return e.extract(string(body))
+}
+
+// builtinExtractors returns the extractors registered by NewRunner
+func (r *Runner) builtinExtractors() []Extractor {
+	return []Extractor{
+		builtinExtractor{
+			name:    ExtractorJSON,
+			match:   func(filename, _ string) bool { return r.isJSONFile(filename) },
+			extract: r.extractFromJSON,
+		},
+		builtinExtractor{
+			name:    ExtractorPnpm,
+			match:   func(filename, _ string) bool { return r.isPnpmLockFile(filename) },
+			extract: r.extractFromPnpmLock,
+		},
+		builtinExtractor{
+			name:    ExtractorYarnrc,
+			match:   func(filename, _ string) bool { return r.isYarnrcFile(filename) },
+			extract: r.extractFromYarnrc,
+		},
+		builtinExtractor{
+			name:    ExtractorNpmrc,
+			match:   func(filename, _ string) bool { return r.isNpmrcFile(filename) },
+			extract: r.extractFromNpmrc,
+		},
+		builtinExtractor{
+			name:    ExtractorManifest,
+			match:   func(filename, _ string) bool { return r.isBuildManifestFile(filename) },
+			extract: r.extractFromBuildManifest,
+		},
+		builtinExtractor{
+			name:    ExtractorConfig,
+			match:   func(filename, _ string) bool { return r.isConfigFile(filename) },
+			extract: r.extractFromConfigFile,
+		},
+		builtinExtractor{
+			name:    ExtractorCICD,
+			match:   func(filename, _ string) bool { return r.isCICDFile(filename) },
+			extract: r.extractFromCICD,
+		},
+		builtinExtractor{
+			name: ExtractorDocs,
+			match: func(filename, contentType string) bool {
+				return r.isDocFile(filename) || contentType == "text/markdown"
+			},
+			extract: r.extractFromDocumentation,
+		},
+		builtinExtractor{
+			name:    ExtractorSourceMap,
+			match:   func(filename, _ string) bool { return r.isSourceMapFile(filename) },
+			extract: r.extractFromSourceMap,
+		},
+		builtinExtractor{
+			name:    ExtractorJavaScript,
+			extract: r.extractFromJavaScript,
+		},
+	}
+}
+
+// AddExtractor registers an extractor, replacing any registered extractor
+// with the same name
+func (r *Runner) AddExtractor(extractor Extractor) {
+	r.extractorsMu.Lock()
+	defer r.extractorsMu.Unlock()
+
+	for i, e := range r.extractors {
+		if e.Name() == extractor.Name() {
+			r.extractors[i] = extractor
+			return
+		}
+	}
+	r.extractors = append(r.extractors, extractor)
+}
+
+// RemoveExtractor unregisters the extractor with the given name and reports
+// whether it was registered
+func (r *Runner) RemoveExtractor(name string) bool {
+	r.extractorsMu.Lock()
+	defer r.extractorsMu.Unlock()
+
+	for i, e := range r.extractors {
+		if e.Name() == name {
+			r.extractors = append(r.extractors[:i:i], r.extractors[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Extractors returns the registered extractors in the order they run
+func (r *Runner) Extractors() []Extractor {
+	r.extractorsMu.RLock()
+	defer r.extractorsMu.RUnlock()
+
+	return append([]Extractor(nil), r.extractors...)
+}
+
+// runExtractor calls the extractor, recovering from panics so a faulty
+// extractor can't take down the scan
+func runExtractor(extractor Extractor, filename, contentType string, body []byte) (packages []Package) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Warnf("Extractor %s failed on %s: %v", extractor.Name(), filename, err)
+			packages = nil
+		}
+	}()
+	return extractor.Extract(filename, contentType, body)
+}
+
+// mediaType returns the media type of a Content-Type header value, without
+// parameters
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
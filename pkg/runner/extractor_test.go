@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+type stubExtractor struct {
+	name        string
+	packages    []string
+	filename    string
+	contentType string
+}
+
+func (e *stubExtractor) Name() string {
+	return e.name
+}
+
+func (e *stubExtractor) Extract(filename, contentType string, body []byte) []Package {
+	e.filename, e.contentType = filename, contentType
+
+	var packages []Package
+	for _, name := range e.packages {
+		if strings.Contains(string(body), name) {
+			pkg, _ := ParsePackageName(name)
+			packages = append(packages, pkg)
+		}
+	}
+	return packages
+}
+
+type panicExtractor struct{}
+
+func (panicExtractor) Name() string { return "panic" }
+
+func (panicExtractor) Extract(string, string, []byte) []Package { panic("boom") }
+
+func hasPackage(packages []Package, name string) bool {
+	for _, pkg := range packages {
+		if pkg.FullName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAddExtractor(t *testing.T) {
+	runner := NewRunner()
+	stub := &stubExtractor{name: "in-house", packages: []string{"@acme/widgets"}}
+	runner.AddExtractor(stub)
+	runner.AddExtractor(panicExtractor{})
+
+	packages := runner.extractPackages("https://example.com/app.txt", "text/plain", []byte("uses @acme/widgets"))
+
+	if !hasPackage(packages, "@acme/widgets") {
+		t.Errorf("expected package from added extractor, got %v", packages)
+	}
+	if stub.filename != "https://example.com/app.txt" || stub.contentType != "text/plain" {
+		t.Errorf("extractor called with (%q, %q)", stub.filename, stub.contentType)
+	}
+
+	// an extractor with the same name replaces the registered one
+	runner.AddExtractor(&stubExtractor{name: "in-house"})
+	if got := len(runner.Extractors()); got != len(runner.builtinExtractors())+2 {
+		t.Errorf("expected extractor to be replaced, got %d extractors", got)
+	}
+	if packages := runner.extractPackages("app.txt", "", []byte("uses @acme/widgets")); hasPackage(packages, "@acme/widgets") {
+		t.Errorf("expected replaced extractor not to run")
+	}
+}
+
+func TestRemoveExtractor(t *testing.T) {
+	runner := NewRunner()
+	body := []byte(`import express from "express"`)
+
+	if !hasPackage(runner.extractPackages("app.js", "", body), "express") {
+		t.Fatalf("expected javascript extractor to find express")
+	}
+
+	if !runner.RemoveExtractor(ExtractorJavaScript) {
+		t.Errorf("expected %s extractor to be registered", ExtractorJavaScript)
+	}
+	if runner.RemoveExtractor(ExtractorJavaScript) {
+		t.Errorf("expected %s extractor to be removed", ExtractorJavaScript)
+	}
+	if packages := runner.extractPackages("app.js", "", body); len(packages) != 0 {
+		t.Errorf("expected no packages without the javascript extractor, got %v", packages)
+	}
+}
@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/root4loot/goutils/log"
+	"github.com/root4loot/goutils/urlutil"
+)
+
+// ScanFile extracts packages from local files and checks them against the
+// registries, sending one result per file to Results. It is equivalent to
+// ScanFileContext with a background context
+func (r *Runner) ScanFile(paths ...string) {
+	r.ScanFileContext(context.Background(), paths...)
+}
+
+// ScanFileContext is like ScanFile but stops scanning when ctx is cancelled
+func (r *Runner) ScanFileContext(ctx context.Context, paths ...string) {
+	if !r.begin() {
+		return
+	}
+	defer r.end()
+
+	r.scanFiles(ctx, paths)
+}
+
+// scanFiles scans the given files concurrently
+func (r *Runner) scanFiles(ctx context.Context, paths []string) {
+	var wg sync.WaitGroup
+
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			log.Warnf("%v", err)
+			continue
+		}
+
+		if urlutil.IsMediaExt(urlutil.GetExt(path)) {
+			continue
+		}
+
+		if !r.claim(abs) {
+			continue
+		}
+
+		select {
+		case r.sem <- struct{}{}:
+		case <-ctx.Done():
+			r.release(abs, false)
+			continue
+		}
+
+		wg.Add(1)
+		go func(path string) {
+			defer func() { <-r.sem }()
+			defer wg.Done()
+			res, ok := r.scanFile(ctx, path)
+			r.release(abs, res.Error == nil && ctx.Err() == nil)
+			if ok {
+				r.sendResult(ctx, res)
+			}
+		}(path)
+	}
+	wg.Wait()
+}
+
+// ScanDir walks the given directories and scans every file, skipping
+// directories listed in Options.IgnoreDirs and, if Options.SkipNodeModules is
+// set, node_modules directories. It is equivalent to ScanDirContext with a
+// background context
+func (r *Runner) ScanDir(roots ...string) {
+	r.ScanDirContext(context.Background(), roots...)
+}
+
+// ScanDirContext is like ScanDir but stops scanning when ctx is cancelled
+func (r *Runner) ScanDirContext(ctx context.Context, roots ...string) {
+	if !r.begin() {
+		return
+	}
+	defer r.end()
+
+	for _, root := range roots {
+		var paths []string
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				log.Warnf("%v", err)
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if d.IsDir() {
+				if path != root && r.isIgnoredDir(d.Name()) {
+					log.Debugf("Skipping directory %s", path)
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if d.Type().IsRegular() {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil && err != ctx.Err() {
+			log.Warnf("%v", err)
+		}
+
+		r.scanFiles(ctx, paths)
+	}
+}
+
+// scanFile reads the file and checks the packages found in it. Binary files
+// are skipped
+func (r *Runner) scanFile(ctx context.Context, path string) (Result, bool) {
+	log.Debugf("Scanning packages in %s", path)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Warnf("%v", err)
+		return Result{RequestURL: path, Error: err}, true
+	}
+
+	if isBinary(content) {
+		log.Debugf("Skipping binary file %s", path)
+		return Result{}, false
+	}
+
+	return Result{
+		RequestURL: path,
+		Packages:   r.checkPackages(ctx, path, mediaType(mime.TypeByExtension(filepath.Ext(path))), content),
+	}, true
+}
+
+func (r *Runner) isIgnoredDir(name string) bool {
+	if r.Options.SkipNodeModules && name == "node_modules" {
+		return true
+	}
+	for _, ignored := range r.Options.IgnoreDirs {
+		if name == ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinary reports whether the content looks like a binary file
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
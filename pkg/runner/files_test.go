@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestScanDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"package.json":                     `{"dependencies": {"express": "^4.18.0", "@company/private-pkg": "1.0.0"}}`,
+		"src/index.js":                     `import helper from 'unclaimed-helper'`,
+		".git/hooks/post-checkout.js":      `require('git-hook-package')`,
+		"node_modules/lodash/package.json": `{"dependencies": {"lodash-internal-dep": "1.0.0"}}`,
+		"assets/binary.dat":                "\x00\x01require('binary-package')",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func(skipNodeModules bool) (sources []string, packages map[string]bool) {
+		runner := newStubRegistry(t, map[string]int{"/express": 200})
+		runner.Options.SkipNodeModules = skipNodeModules
+		packages = make(map[string]bool)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range runner.Results {
+				rel, _ := filepath.Rel(root, result.RequestURL)
+				sources = append(sources, filepath.ToSlash(rel))
+				for _, pkg := range result.Packages {
+					packages[pkg.FullName()] = pkg.IsClaimed()
+				}
+			}
+		}()
+
+		runner.ScanDir(root)
+		runner.Close()
+		<-done
+		sort.Strings(sources)
+		return
+	}
+
+	sources, packages := scan(false)
+	expected := []string{"node_modules/lodash/package.json", "package.json", "src/index.js"}
+	if len(sources) != len(expected) {
+		t.Fatalf("expected sources %v, got %v", expected, sources)
+	}
+	for i := range expected {
+		if sources[i] != expected[i] {
+			t.Errorf("expected sources %v, got %v", expected, sources)
+			break
+		}
+	}
+
+	for name, claimed := range map[string]bool{"express": true, "@company/private-pkg": false, "unclaimed-helper": false, "lodash-internal-dep": false} {
+		if got, ok := packages[name]; !ok || got != claimed {
+			t.Errorf("%s: expected detected with claimed=%v, got detected=%v claimed=%v", name, claimed, ok, got)
+		}
+	}
+	for _, name := range []string{"git-hook-package", "binary-package"} {
+		if _, ok := packages[name]; ok {
+			t.Errorf("%s: expected to be skipped", name)
+		}
+	}
+
+	sources, packages = scan(true)
+	if _, ok := packages["lodash-internal-dep"]; len(sources) != 2 || ok {
+		t.Errorf("expected node_modules to be skipped, got %v", sources)
+	}
+}
@@ -0,0 +1,405 @@
+package runner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// moduleSpecifier is a module specifier found in an import, export or require
+type moduleSpecifier struct {
+	specifier string // specifier as written, e.g. "lodash/fp" or "./utils"
+	method    string // import-from, export-from, dynamic-import, require-import or require-resolve
+	offset    int    // offset of the specifier in the source
+}
+
+type tokenKind int
+
+const (
+	tokenEOF      tokenKind = iota
+	tokenIdent              // identifier or keyword
+	tokenString             // string literal, or template literal without substitutions
+	tokenTemplate           // part of a template literal with substitutions
+	tokenNumber             // numeric literal
+	tokenRegexp             // regular expression literal
+	tokenPunct              // single punctuation character
+)
+
+type token struct {
+	kind   tokenKind
+	text   string // source text, without quotes for strings
+	offset int    // offset of text in the source
+}
+
+// regexpKeywords are keywords after which a slash starts a regular
+// expression rather than a division
+var regexpKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+// jsLexer is a minimal JavaScript and TypeScript tokenizer. It skips
+// comments and tells strings, template literals and regular expressions
+// apart from code, which is all that's needed to find module specifiers.
+// Strings and regular expressions end at a newline even when unterminated,
+// so non-JavaScript content such as HTML or prose can't derail it for more
+// than a line
+type jsLexer struct {
+	src    string
+	pos    int
+	prev   token  // last token scanned
+	before token  // token scanned before prev
+	braces []bool // open braces, true for template substitutions
+}
+
+// lexModuleSpecifiers returns the module specifiers of the import and export
+// declarations, require and require.resolve calls and dynamic imports in src
+func lexModuleSpecifiers(src string) []moduleSpecifier {
+	l := &jsLexer{src: src}
+	var specifiers []moduleSpecifier
+
+	tok := l.next()
+	for tok.kind != tokenEOF {
+		if tok.kind != tokenIdent || l.isPropertyName() {
+			tok = l.next()
+			continue
+		}
+
+		switch tok.text {
+		case "import":
+			tok = l.scanImport(&specifiers)
+		case "export":
+			tok = l.scanExport(&specifiers)
+		case "require":
+			tok = l.scanRequire(&specifiers)
+		default:
+			tok = l.next()
+		}
+	}
+
+	return specifiers
+}
+
+// scanImport scans the rest of an import declaration or dynamic import and
+// returns the first token after it
+func (l *jsLexer) scanImport(specifiers *[]moduleSpecifier) token {
+	tok := l.next()
+
+	switch {
+	case tok.kind == tokenPunct && tok.text == "(":
+		return l.scanCall(specifiers, "dynamic-import")
+	case tok.kind == tokenString:
+		// import "side-effect"
+		*specifiers = append(*specifiers, newModuleSpecifier(tok, "import-from"))
+		return l.next()
+	case tok.kind == tokenPunct && tok.text == ".":
+		// import.meta
+		return tok
+	}
+
+	return l.scanFromClause(tok, specifiers, "import-from")
+}
+
+// scanExport scans the rest of a re-export and returns the first token after
+// it. Other exports are left to the caller
+func (l *jsLexer) scanExport(specifiers *[]moduleSpecifier) token {
+	tok := l.next()
+
+	if tok.kind == tokenPunct && (tok.text == "*" || tok.text == "{") || tok.kind == tokenIdent && tok.text == "type" {
+		return l.scanFromClause(tok, specifiers, "export-from")
+	}
+	return tok
+}
+
+// scanRequire scans the rest of a require or require.resolve call and
+// returns the first token after it
+func (l *jsLexer) scanRequire(specifiers *[]moduleSpecifier) token {
+	tok := l.next()
+	method := "require-import"
+
+	if tok.kind == tokenPunct && tok.text == "." {
+		if tok = l.next(); tok.kind != tokenIdent || tok.text != "resolve" {
+			return tok
+		}
+		method = "require-resolve"
+		tok = l.next()
+	}
+
+	if tok.kind != tokenPunct || tok.text != "(" {
+		return tok
+	}
+	return l.scanCall(specifiers, method)
+}
+
+// scanCall scans the arguments of an import() or require() call, starting
+// after the opening parenthesis, and records a string literal first argument
+func (l *jsLexer) scanCall(specifiers *[]moduleSpecifier, method string) token {
+	arg := l.next()
+	if arg.kind != tokenString {
+		return arg
+	}
+
+	tok := l.next()
+	if tok.kind == tokenPunct && (tok.text == ")" || tok.text == ",") {
+		*specifiers = append(*specifiers, newModuleSpecifier(arg, method))
+	}
+	return tok
+}
+
+// scanFromClause scans the bindings of an import or export declaration up to
+// and including `from "specifier"`. It stops at the first token that can't
+// be part of the clause and returns the first token after it
+func (l *jsLexer) scanFromClause(tok token, specifiers *[]moduleSpecifier, method string) token {
+	depth := 0 // depth of braces in the clause
+
+	for tok.kind != tokenEOF {
+		switch {
+		case tok.kind == tokenIdent && tok.text == "from" && depth == 0:
+			from := l.next()
+			if from.kind != tokenString {
+				return from
+			}
+			*specifiers = append(*specifiers, newModuleSpecifier(from, method))
+			return l.next()
+		case tok.kind == tokenPunct && tok.text == "{":
+			depth++
+		case tok.kind == tokenPunct && tok.text == "}":
+			if depth--; depth < 0 {
+				return tok
+			}
+		case tok.kind == tokenIdent, tok.kind == tokenPunct && (tok.text == "*" || tok.text == ","):
+		case tok.kind == tokenString && depth > 0:
+			// import { "string name" as name }
+		default:
+			return tok
+		}
+		tok = l.next()
+	}
+
+	return tok
+}
+
+func newModuleSpecifier(tok token, method string) moduleSpecifier {
+	return moduleSpecifier{specifier: unescapeJS(tok.text), method: method, offset: tok.offset}
+}
+
+// isPropertyName reports whether the last token follows a dot, as in
+// x.require(...). A spread (...require) is not a property access, and
+// neither is a dot followed by a line break, which is more likely the end
+// of a sentence in prose around the code
+func (l *jsLexer) isPropertyName() bool {
+	if l.before.kind != tokenPunct || l.before.text != "." {
+		return false
+	}
+	if l.before.offset > 0 && l.src[l.before.offset-1] == '.' {
+		return false
+	}
+	return !strings.Contains(l.src[l.before.offset:l.prev.offset], "\n")
+}
+
+// next returns the next token, skipping whitespace and comments
+func (l *jsLexer) next() token {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case c == '/' && l.peek(1) == '/':
+			if end := strings.IndexByte(l.src[l.pos:], '\n'); end != -1 {
+				l.pos += end + 1
+			} else {
+				l.pos = len(l.src)
+			}
+		case c == '/' && l.peek(1) == '*':
+			if end := strings.Index(l.src[l.pos+2:], "*/"); end != -1 {
+				l.pos += end + 4
+			} else {
+				l.pos = len(l.src)
+			}
+		default:
+			tok := l.scan()
+			l.before, l.prev = l.prev, tok
+			return tok
+		}
+	}
+
+	return token{kind: tokenEOF, offset: len(l.src)}
+}
+
+// scan scans the token starting at the current position
+func (l *jsLexer) scan() token {
+	start := l.pos
+	c := l.src[l.pos]
+
+	switch {
+	case c == '"' || c == '\'':
+		return l.scanString(c)
+	case c == '`':
+		l.pos++
+		return l.scanTemplate(true)
+	case c == '}' && len(l.braces) > 0 && l.braces[len(l.braces)-1]:
+		// end of a template substitution
+		l.braces = l.braces[:len(l.braces)-1]
+		l.pos++
+		return l.scanTemplate(false)
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], offset: start}
+	case isDigit(c) || c == '.' && isDigit(l.peek(1)):
+		for l.pos < len(l.src) && (isIdentPart(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokenNumber, text: l.src[start:l.pos], offset: start}
+	case c == '/' && l.regexpAllowed():
+		return l.scanRegexp()
+	}
+
+	switch c {
+	case '{':
+		l.braces = append(l.braces, false)
+	case '}':
+		if len(l.braces) > 0 {
+			l.braces = l.braces[:len(l.braces)-1]
+		}
+	}
+	l.pos++
+	return token{kind: tokenPunct, text: l.src[start:l.pos], offset: start}
+}
+
+// scanString scans a string literal. The token holds the raw contents
+func (l *jsLexer) scanString(quote byte) token {
+	l.pos++
+	start := l.pos
+
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+		case quote:
+			l.pos++
+			return token{kind: tokenString, text: l.src[start : l.pos-1], offset: start}
+		case '\n':
+			return token{kind: tokenString, text: l.src[start:l.pos], offset: start}
+		default:
+			l.pos++
+		}
+	}
+
+	l.pos = len(l.src)
+	return token{kind: tokenString, text: l.src[start:], offset: start}
+}
+
+// scanTemplate scans template literal text up to the closing backtick or the
+// next substitution. A template without substitutions is returned as a string
+func (l *jsLexer) scanTemplate(head bool) token {
+	start := l.pos
+
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+		case '`':
+			l.pos++
+			kind := tokenTemplate
+			if head {
+				kind = tokenString
+			}
+			return token{kind: kind, text: l.src[start : l.pos-1], offset: start}
+		case '$':
+			if l.peek(1) == '{' {
+				l.braces = append(l.braces, true)
+				l.pos += 2
+				return token{kind: tokenTemplate, text: l.src[start : l.pos-2], offset: start}
+			}
+			l.pos++
+		default:
+			l.pos++
+		}
+	}
+
+	l.pos = len(l.src)
+	return token{kind: tokenTemplate, text: l.src[start:], offset: start}
+}
+
+// scanRegexp scans a regular expression literal and its flags
+func (l *jsLexer) scanRegexp() token {
+	start := l.pos
+	l.pos++
+	inClass := false
+
+scan:
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+			continue
+		case '\n':
+			break scan
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				l.pos++
+				break scan
+			}
+		}
+		l.pos++
+	}
+
+	for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+		l.pos++
+	}
+	l.pos = min(l.pos, len(l.src))
+	return token{kind: tokenRegexp, text: l.src[start:l.pos], offset: start}
+}
+
+// regexpAllowed reports whether a slash at the current position starts a
+// regular expression, judging by the previous token
+func (l *jsLexer) regexpAllowed() bool {
+	switch l.prev.kind {
+	case tokenEOF:
+		return true
+	case tokenIdent:
+		return regexpKeywords[l.prev.text]
+	case tokenPunct:
+		return l.prev.text != ")" && l.prev.text != "]"
+	default:
+		return false
+	}
+}
+
+func (l *jsLexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// unescapeJS resolves escape sequences in the contents of a string literal,
+// returning it unchanged if it can't be decoded
+func unescapeJS(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	if unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(s, `\'`, `'`) + `"`); err == nil {
+		return unquoted
+	}
+	return s
+}
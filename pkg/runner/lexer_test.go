@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLexModuleSpecifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string // "method specifier"
+	}{
+		{
+			name:     "multi-line import",
+			src:      "import {\n  a,\n  b as c\n} from 'multi-line'",
+			expected: []string{"import-from multi-line"},
+		},
+		{
+			name:     "default, namespace and side-effect imports",
+			src:      `import x from "a"; import * as ns from "b"; import y, { z } from "c"; import "d"`,
+			expected: []string{"import-from a", "import-from b", "import-from c", "import-from d"},
+		},
+		{
+			name:     "re-exports",
+			src:      "export * from 'a'\nexport * as ns from 'b'\nexport { x, y as z } from 'c'\nexport { local }\nexport const from = 'not-a-module'",
+			expected: []string{"export-from a", "export-from b", "export-from c"},
+		},
+		{
+			name:     "typescript",
+			src:      "import type { T } from 'types-pkg'\nexport type { U } from 'other-types'\nimport fs = require('ts-require')",
+			expected: []string{"import-from types-pkg", "export-from other-types", "require-import ts-require"},
+		},
+		{
+			name:     "minified",
+			src:      `import{a as b}from"x";export*from"y";const c=require("z"),d=import("w")`,
+			expected: []string{"import-from x", "export-from y", "require-import z", "dynamic-import w"},
+		},
+		{
+			name:     "comments",
+			src:      "// import a from 'line-comment'\n/* require('block-comment') */\nrequire('real')",
+			expected: []string{"require-import real"},
+		},
+		{
+			name:     "strings and templates",
+			src:      "const s = \"import a from 'in-string'\" + 'require(\"x\")';\nconst t = `import b from 'in-template' ${require('in-substitution')} ${ {a: 1}.a }`;\nrequire(`template-arg`)",
+			expected: []string{"require-import in-substitution", "require-import template-arg"},
+		},
+		{
+			name:     "regular expressions",
+			src:      "const re = /['\"](require)\\//g; if (/[/'\"]/.test(s)) require('after-regex')\nconst half = a / 2; require('after-division') / 2",
+			expected: []string{"require-import after-regex", "require-import after-division"},
+		},
+		{
+			name:     "property access",
+			src:      "obj.require('method'); x.import('y'); import.meta.url; [...require('spread')]",
+			expected: []string{"require-import spread"},
+		},
+		{
+			name:     "require.resolve and dynamic import",
+			src:      "require.resolve('resolved', { paths }); await import('./lazy.js'); import(name)",
+			expected: []string{"require-resolve resolved", "dynamic-import ./lazy.js"},
+		},
+		{
+			name:     "binding named from",
+			src:      "import { from } from 'named-from'",
+			expected: []string{"import-from named-from"},
+		},
+		{
+			name:     "prose",
+			src:      "Don't worry, it's fine.\nrequire('after-prose')",
+			expected: []string{"require-import after-prose"},
+		},
+		{
+			name:     "escapes",
+			src:      `require("esc\x61ped")`,
+			expected: []string{"require-import escaped"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, spec := range lexModuleSpecifiers(tt.src) {
+				got = append(got, spec.method+" "+spec.specifier)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLexModuleSpecifierOffsets(t *testing.T) {
+	src := "const a = 1;\nimport b from 'pkg'"
+
+	specs := lexModuleSpecifiers(src)
+	if len(specs) != 1 {
+		t.Fatalf("expected one specifier, got %v", specs)
+	}
+	if got := src[specs[0].offset : specs[0].offset+len("pkg")]; got != "pkg" {
+		t.Errorf("expected offset of pkg, got %q", got)
+	}
+}
+
+func BenchmarkLexModuleSpecifiers(b *testing.B) {
+	chunk := "var a=require(\"react\"),b=/[\"']/g,c=`x${d}y`;/* comment */function e(f){return f/2}import(\"lazy\");\n"
+	src := strings.Repeat(chunk, 50000) // ~5MB
+
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lexModuleSpecifiers(src)
+	}
+}
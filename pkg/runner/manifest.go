@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// viteManifestEntry is an entry of a Vite or Rollup build manifest
+// (.vite/manifest.json), keyed by the input path of the chunk
+type viteManifestEntry struct {
+	File           string   `json:"file"`
+	Src            string   `json:"src"`
+	Imports        []string `json:"imports"`
+	DynamicImports []string `json:"dynamicImports"`
+}
+
+// esbuildMetafile is the metafile esbuild writes with --metafile
+type esbuildMetafile struct {
+	Inputs map[string]struct {
+		Imports []esbuildImport `json:"imports"`
+	} `json:"inputs"`
+	Outputs map[string]json.RawMessage `json:"outputs"`
+}
+
+type esbuildImport struct {
+	Path     string `json:"path"`
+	Original string `json:"original"`
+	External bool   `json:"external"`
+}
+
+// isBuildManifestFile reports whether the file is named like a Vite build
+// manifest (manifest.json, or .vite/manifest.json since Vite 5) or an
+// esbuild metafile (meta.json, metafile-esm.json, ...)
+func (r *Runner) isBuildManifestFile(url string) bool {
+	name := strings.ToLower(path.Base(trimURLParams(url)))
+	return name == "manifest.json" || name == "meta.json" ||
+		strings.HasPrefix(name, "metafile") && strings.HasSuffix(name, ".json")
+}
+
+// parseBuildManifest parses a Vite manifest or an esbuild metafile, telling
+// them apart by their shape. Other JSON, such as a web app manifest.json,
+// is neither
+func parseBuildManifest(content string) (map[string]viteManifestEntry, *esbuildMetafile) {
+	var metafile esbuildMetafile
+	if err := json.Unmarshal([]byte(content), &metafile); err == nil && metafile.Inputs != nil && metafile.Outputs != nil {
+		return nil, &metafile
+	}
+
+	var manifest map[string]viteManifestEntry
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		return nil, nil
+	}
+	for _, entry := range manifest {
+		if entry.File != "" {
+			return manifest, nil
+		}
+	}
+	return nil, nil
+}
+
+// extractFromBuildManifest finds the packages bundled by a build, named by
+// the node_modules input paths of a Vite manifest or esbuild metafile and
+// by the imports esbuild recorded
+func (r *Runner) extractFromBuildManifest(content string) []Package {
+	var packages []Package
+
+	manifest, metafile := parseBuildManifest(content)
+	for key, entry := range manifest {
+		for _, input := range []string{key, entry.Src} {
+			if name, i, ok := nodeModulesPackage(input); ok {
+				packages = append(packages, r.createPackageFromName(name, "vite-manifest", offsetAt(indexQuoted(content, input), i)))
+			}
+		}
+	}
+
+	if metafile == nil {
+		return packages
+	}
+	for key, input := range metafile.Inputs {
+		if name, i, ok := nodeModulesPackage(key); ok {
+			packages = append(packages, r.createPackageFromName(name, "esbuild-metafile", offsetAt(indexQuoted(content, key), i)))
+		}
+
+		for _, imp := range input.Imports {
+			// original is the specifier as written, path where it resolved to
+			switch {
+			case imp.Original != "":
+				if name, ok := r.specifierPackageName(imp.Original); ok {
+					packages = append(packages, r.createPackageFromName(name, "esbuild-metafile", indexQuoted(content, imp.Original)))
+				}
+			case imp.External:
+				if name, ok := r.specifierPackageName(imp.Path); ok {
+					packages = append(packages, r.createPackageFromName(name, "esbuild-metafile", indexQuoted(content, imp.Path)))
+				}
+			default:
+				if name, i, ok := nodeModulesPackage(imp.Path); ok {
+					packages = append(packages, r.createPackageFromName(name, "esbuild-metafile", offsetAt(indexQuoted(content, imp.Path), i)))
+				}
+			}
+		}
+	}
+
+	return packages
+}
+
+// buildManifestLinks returns the URLs of the script chunks a Vite manifest
+// or esbuild metafile lists. Vite chunks are relative to the build output
+// directory, which holds the manifest or, since Vite 5, its .vite
+// directory. esbuild outputs are taken to be relative to the metafile
+func buildManifestLinks(content string, manifestURL *url.URL) []link {
+	if manifestURL == nil {
+		return nil
+	}
+
+	var files []string
+	base := manifestURL.ResolveReference(&url.URL{Path: "./"})
+
+	manifest, metafile := parseBuildManifest(content)
+	for _, entry := range manifest {
+		files = append(files, entry.File)
+	}
+	if manifest != nil && strings.HasSuffix(base.Path, "/.vite/") {
+		base = base.ResolveReference(&url.URL{Path: "../"})
+	}
+	if metafile != nil {
+		for output := range metafile.Outputs {
+			files = append(files, output)
+		}
+	}
+	sort.Strings(files)
+
+	var links []link
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if ext := path.Ext(file); ext != ".js" && ext != ".mjs" && ext != ".cjs" {
+			continue
+		}
+		ref, err := url.Parse(strings.TrimPrefix(file, "/"))
+		if err != nil {
+			continue
+		}
+		if u := base.ResolveReference(ref).String(); !seen[u] {
+			seen[u] = true
+			links = append(links, link{url: u, crawl: true})
+		}
+	}
+	return links
+}
+
+// nodeModulesPackage returns the package an input path such as
+// node_modules/.pnpm/lodash-es@4.17.21/node_modules/lodash-es/lodash.js
+// belongs to, and the index of its name in the path
+func nodeModulesPackage(inputPath string) (string, int, bool) {
+	i := strings.LastIndex(inputPath, "node_modules/")
+	if i == -1 {
+		return "", 0, false
+	}
+	i += len("node_modules/")
+
+	name, ok := NormalizeSpecifier(inputPath[i:])
+	return name, i, ok
+}
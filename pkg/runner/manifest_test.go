@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestIsBuildManifestFile(t *testing.T) {
+	runner := NewRunner()
+	tests := map[string]bool{
+		"https://example.com/.vite/manifest.json":        true,
+		"https://example.com/dist/manifest.json?v=3":     true,
+		"https://example.com/meta.json":                  true,
+		"https://example.com/build/metafile-esm.json":    true,
+		"https://example.com/package.json":               false,
+		"https://example.com/asset-manifest.json":        false,
+		"https://example.com/manifest.json.map":          false,
+		"https://example.com/static/js/manifest.json.js": false,
+	}
+	for url, expected := range tests {
+		if got := runner.isBuildManifestFile(url); got != expected {
+			t.Errorf("%s: expected %v, got %v", url, expected, got)
+		}
+		if expected && runner.isJSONFile(url) {
+			t.Errorf("%s: expected not to be read as package.json", url)
+		}
+	}
+}
+
+func TestExtractFromBuildManifest(t *testing.T) {
+	runner := NewRunner()
+
+	vite := `{"node_modules/.pnpm/company-ui@1.0.0/node_modules/company-ui/index.js":{"file":"assets/company-ui.js"}}`
+	pkg, ok := findPackage(runner.extractFromBuildManifest(vite), "company-ui")
+	if !ok {
+		t.Fatalf("expected company-ui from the Vite manifest")
+	}
+	if e := pkg.Evidence[0]; e.Method != "vite-manifest" || e.Offset != len(`{"node_modules/.pnpm/company-ui@1.0.0/node_modules/`) {
+		t.Errorf("unexpected evidence %+v", e)
+	}
+
+	esbuild := `{"inputs":{"src/a.js":{"imports":[{"path":"node_modules/company-core/index.js","original":"company-core/sub"},{"path":"./b.js","original":"./b.js"}]}},"outputs":{}}`
+	pkg, ok = findPackage(runner.extractFromBuildManifest(esbuild), "company-core")
+	if !ok {
+		t.Fatalf("expected company-core from the esbuild metafile")
+	}
+	if e := pkg.Evidence[0]; e.Method != "esbuild-metafile" || e.Offset != indexQuoted(esbuild, "company-core/sub") {
+		t.Errorf("unexpected evidence %+v", e)
+	}
+
+	pwa := `{"name":"company-app","short_name":"app","start_url":"/","icons":[]}`
+	if packages := runner.extractFromBuildManifest(pwa); len(packages) != 0 {
+		t.Errorf("expected nothing from a web app manifest, got %v", packages)
+	}
+}
+
+func TestBuildManifestLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		manifest string
+		urls     []string
+	}{
+		{
+			name:     "vite 5",
+			url:      "https://example.com/.vite/manifest.json",
+			manifest: `{"index.html":{"file":"assets/index-1a.js","css":["assets/index-2b.css"]},"_vendor.js":{"file":"assets/vendor-3c.js"},"style.css":{"file":"assets/style-4d.css"}}`,
+			urls:     []string{"https://example.com/assets/index-1a.js", "https://example.com/assets/vendor-3c.js"},
+		},
+		{
+			name:     "vite 4",
+			url:      "https://example.com/app/manifest.json",
+			manifest: `{"main.ts":{"file":"assets/main-5e.js"}}`,
+			urls:     []string{"https://example.com/app/assets/main-5e.js"},
+		},
+		{
+			name:     "esbuild",
+			url:      "https://example.com/meta.json",
+			manifest: `{"inputs":{},"outputs":{"dist/main.js":{},"dist/main.js.map":{},"dist/chunk-X.mjs":{}}}`,
+			urls:     []string{"https://example.com/dist/chunk-X.mjs", "https://example.com/dist/main.js"},
+		},
+		{
+			name:     "web app manifest",
+			url:      "https://example.com/manifest.json",
+			manifest: `{"name":"app","start_url":"/index.js"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		base, _ := url.Parse(tt.url)
+		var urls []string
+		for _, l := range buildManifestLinks(tt.manifest, base) {
+			if !l.crawl {
+				t.Errorf("%s: expected %s to be a crawl link", tt.name, l.url)
+			}
+			urls = append(urls, l.url)
+		}
+		if !reflect.DeepEqual(urls, tt.urls) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.urls, urls)
+		}
+	}
+}
+
+func TestRunCrawlsBuildManifestChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/.vite/manifest.json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"src/admin.ts":{"file":"assets/admin-9a.js","isDynamicEntry":true}}`)
+		case "/assets/admin-9a.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `import { audit } from "company-admin-audit";`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	runner := newStubRegistry(t, nil)
+	runner.Options.CrawlDepth = 1
+	results := collectResults(runner)
+
+	runner.RunContext(context.Background(), server.URL+"/.vite/manifest.json")
+	runner.Close()
+
+	for _, result := range <-results {
+		if result.RequestURL != server.URL+"/assets/admin-9a.js" {
+			continue
+		}
+		if !reflect.DeepEqual(result.Referrers, []string{server.URL + "/.vite/manifest.json"}) {
+			t.Errorf("expected the manifest as referrer, got %v", result.Referrers)
+		}
+		if _, ok := findPackage(result.Packages, "company-admin-audit"); !ok {
+			t.Errorf("expected company-admin-audit, got %v", result.Packages)
+		}
+		return
+	}
+	t.Errorf("expected the chunk to be scanned")
+}
@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+const maxPackageNameLength = 214
+
+// reservedPackageNames can never be published to the npm registry
+var reservedPackageNames = map[string]bool{
+	"node_modules": true,
+	"favicon.ico":  true,
+}
+
+// ParsePackageName splits an npm package name such as @babel/core into its
+// namespace (@babel) and bare name (core), validating it against npm's
+// naming rules for new packages
+func ParsePackageName(name string) (Package, error) {
+	if err := ValidatePackageName(name); err != nil {
+		return Package{}, err
+	}
+
+	if strings.HasPrefix(name, "@") {
+		slash := strings.Index(name, "/")
+		return Package{Namespace: name[:slash], Name: name[slash+1:]}, nil
+	}
+
+	return Package{Name: name}, nil
+}
+
+// ValidatePackageName reports whether the name could be published to the npm
+// registry. Names that can't be published are never dependency confusion
+// candidates, so they are rejected before any registry lookup
+func ValidatePackageName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("invalid package name: empty")
+	case len(name) > maxPackageNameLength:
+		return fmt.Errorf("invalid package name %q: longer than %d characters", name, maxPackageNameLength)
+	case strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"):
+		return fmt.Errorf("invalid package name %q: starts with a period or underscore", name)
+	case reservedPackageNames[name]:
+		return fmt.Errorf("invalid package name %q: reserved name", name)
+	}
+
+	parts := []string{name}
+	if strings.HasPrefix(name, "@") {
+		parts = strings.Split(name[1:], "/")
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid package name %q: scoped names must look like @scope/name", name)
+		}
+	}
+
+	for _, part := range parts {
+		if err := validateNamePart(part); err != nil {
+			return fmt.Errorf("invalid package name %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateNamePart validates a scope or bare name. Only lowercase letters,
+// digits, hyphens, periods and underscores are URL-safe and allowed in new
+// package names
+func validateNamePart(part string) error {
+	if part == "" {
+		return fmt.Errorf("empty name segment")
+	}
+	if part[0] == '.' || part[0] == '_' {
+		return fmt.Errorf("segment starts with a period or underscore")
+	}
+
+	for _, c := range part {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.', c == '_':
+		case c >= 'A' && c <= 'Z':
+			return fmt.Errorf("contains uppercase letters")
+		default:
+			return fmt.Errorf("contains invalid character %q", c)
+		}
+	}
+
+	return nil
+}
+
+// ValidateScopeName reports whether the scope (e.g. @company) could be
+// registered as an npm organization or user
+func ValidateScopeName(scope string) error {
+	if !strings.HasPrefix(scope, "@") {
+		return fmt.Errorf("invalid scope %q: must start with @", scope)
+	}
+	if err := validateNamePart(scope[1:]); err != nil {
+		return fmt.Errorf("invalid scope %q: %v", scope, err)
+	}
+	return nil
+}
+
+// validate validates the package name, or the scope of a scope finding.
+// Registry findings have no name to validate
+func (p Package) validate() error {
+	if p.IsRegistryOnly() {
+		return nil
+	}
+	if p.IsScopeOnly() {
+		return ValidateScopeName(p.Namespace)
+	}
+	return ValidatePackageName(p.FullName())
+}
+
+// IsScoped reports whether the package belongs to a scope (e.g. @babel)
+func (p Package) IsScoped() bool {
+	return p.Namespace != ""
+}
+
+// IsScopeOnly reports whether the package stands for a whole scope rather
+// than a single package, as found in registry configuration that routes the
+// scope to a private registry
+func (p Package) IsScopeOnly() bool {
+	return p.Namespace != "" && p.Name == ""
+}
+
+// IsRegistryOnly reports whether the package stands for a private registry
+// that is authenticated to, without a scope or package routed to it
+func (p Package) IsRegistryOnly() bool {
+	return p.Namespace == "" && p.Name == "" && p.ScopeRegistry != ""
+}
+
+// FullName returns the name as published on the registry, including the
+// namespace for scoped packages (e.g. @babel/core), just the scope for
+// scope findings, or the registry for registry findings
+func (p Package) FullName() string {
+	if p.IsRegistryOnly() {
+		return p.ScopeRegistry
+	}
+	if p.IsScopeOnly() {
+		return p.Namespace
+	}
+	if p.IsScoped() {
+		return p.Namespace + "/" + p.Name
+	}
+	return p.Name
+}
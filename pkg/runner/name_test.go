@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePackageName(t *testing.T) {
+	tests := []struct {
+		input     string
+		namespace string
+		name      string
+		valid     bool
+	}{
+		{"express", "", "express", true},
+		{"chart.js", "", "chart.js", true},
+		{"@babel/core", "@babel", "core", true},
+		{"@company/private-pkg", "@company", "private-pkg", true},
+		{"React", "", "", false},
+		{"@Company/pkg", "", "", false},
+		{".hidden", "", "", false},
+		{"_private", "", "", false},
+		{"@scope/.hidden", "", "", false},
+		{"@scope", "", "", false},
+		{"@scope/a/b", "", "", false},
+		{"has space", "", "", false},
+		{"node_modules", "", "", false},
+		{"bad~name", "", "", false},
+		{strings.Repeat("a", 215), "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		pkg, err := ParsePackageName(tt.input)
+		if tt.valid != (err == nil) {
+			t.Errorf("%q: expected valid=%v, got error %v", tt.input, tt.valid, err)
+			continue
+		}
+		if !tt.valid {
+			continue
+		}
+		if pkg.Namespace != tt.namespace || pkg.Name != tt.name {
+			t.Errorf("%q: expected namespace %q name %q, got %q %q", tt.input, tt.namespace, tt.name, pkg.Namespace, pkg.Name)
+		}
+		if pkg.FullName() != tt.input {
+			t.Errorf("%q: FullName() returned %q", tt.input, pkg.FullName())
+		}
+		if pkg.IsScoped() != (tt.namespace != "") {
+			t.Errorf("%q: IsScoped() returned %v", tt.input, pkg.IsScoped())
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"strings"
+)
+
+// npmrcAuthKeys are the settings that make npm or yarn authenticate to a
+// registry, which marks the registry as private
+var npmrcAuthKeys = map[string]bool{
+	"always-auth": true,
+	"_authToken":  true,
+	"_auth":       true,
+	"_password":   true,
+	"username":    true,
+	"certfile":    true,
+	"keyfile":     true,
+}
+
+// publicRegistryHosts are the hosts of the public npm registry and its
+// mirrors, which scopes may be routed to without being private
+var publicRegistryHosts = []string{
+	"//registry.npmjs.org",
+	"//registry.npmjs.com",
+	"//registry.yarnpkg.com",
+	"//registry.npmmirror.com",
+}
+
+// npmrcSetting is a key and value of an .npmrc or .yarnrc line
+type npmrcSetting struct {
+	key    string
+	value  string
+	offset int // offset of the key in the content
+}
+
+func (r *Runner) isNpmrcFile(url string) bool {
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		url = url[:i]
+	}
+	lowerURL := strings.ToLower(url)
+	return strings.HasSuffix(lowerURL, ".npmrc") || strings.HasSuffix(lowerURL, ".yarnrc")
+}
+
+// npmrcAuth is a registry that an .npmrc or .yarnrc authenticates to
+type npmrcAuth struct {
+	host    string // registry URL without scheme, as returned by registryHost
+	offset  int    // offset of the first auth setting for the registry
+	matched bool   // whether a scope is routed to the registry
+}
+
+// extractFromNpmrc finds the scopes that an .npmrc or yarn v1 .yarnrc routes
+// to a registry of their own (@company:registry=https://npm.company.com/),
+// which are reported as private scope findings unless the registry is a
+// public one. Settings that authenticate to the registry of a scope
+// (//npm.company.com/:_authToken=... or always-auth) are added to the scope
+// as evidence. Private registries authenticated to without a scope routed to
+// them are reported as registry findings
+func (r *Runner) extractFromNpmrc(content string) []Package {
+	var packages []Package
+	var settings []npmrcSetting
+	defaultRegistry := ""
+
+	offset := 0
+	for _, line := range strings.Split(content, "\n") {
+		lineStart := offset
+		offset += len(line) + 1
+
+		if setting, ok := parseNpmrcLine(line, lineStart); ok {
+			settings = append(settings, setting)
+			if setting.key == "registry" {
+				defaultRegistry = setting.value
+			}
+		}
+	}
+
+	// registries that are authenticated to, in the order of the file
+	var auths []npmrcAuth
+	authHosts := make(map[string]bool)
+	for _, s := range settings {
+		host, key := "", s.key
+		if strings.HasPrefix(key, "//") {
+			if i := strings.LastIndex(key, ":"); i != -1 {
+				host, key = registryHost(key[:i]), key[i+1:]
+			}
+		} else {
+			host = registryHost(defaultRegistry)
+		}
+		if host == "" || !npmrcAuthKeys[key] || s.value == "false" || authHosts[host] {
+			continue
+		}
+		authHosts[host] = true
+		auths = append(auths, npmrcAuth{host: host, offset: s.offset})
+	}
+
+	for _, s := range settings {
+		scope, ok := strings.CutSuffix(s.key, ":registry")
+		if !ok || !strings.HasPrefix(scope, "@") || r.isPublicRegistry(s.value) {
+			continue
+		}
+
+		pkg := r.createScopeFromName(scope, "npmrc-scope", s.offset)
+		pkg.ScopeRegistry = redactSecrets(s.value)
+		for i := range auths {
+			if registryMatches(registryHost(s.value), auths[i].host) {
+				auths[i].matched = true
+				pkg.Evidence = append(pkg.Evidence, Evidence{Method: "npmrc-auth", Offset: auths[i].offset})
+			}
+		}
+		packages = append(packages, pkg)
+	}
+
+	for _, auth := range auths {
+		if auth.matched || r.isPublicRegistry(auth.host) {
+			continue
+		}
+		packages = append(packages, Package{
+			ScopeRegistry: redactSecrets(auth.host + "/"),
+			Evidence:      []Evidence{{Method: "npmrc-auth", Offset: auth.offset}},
+		})
+	}
+
+	return packages
+}
+
+// registryMatches reports whether the registry is covered by settings for
+// host, which like npm matches the registry host and leading path segments
+// (//npm.company.com/ covers //npm.company.com/npm/ but not
+// //npm.company.com.evil.net/)
+func registryMatches(registry, host string) bool {
+	registry, host = strings.ToLower(registry), strings.ToLower(host)
+	return registry == host || strings.HasPrefix(registry, host+"/")
+}
+
+// isPublicRegistry reports whether the registry URL is the public npm
+// registry, a well-known mirror of it or a configured public registry
+func (r *Runner) isPublicRegistry(registry string) bool {
+	host := strings.ToLower(registryHost(registry))
+	for _, public := range publicRegistryHosts {
+		if host == public {
+			return true
+		}
+	}
+	for _, reg := range r.registries(RolePublic) {
+		if host == strings.ToLower(registryHost(reg.URL)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNpmrcLine parses an .npmrc line (key=value) or a yarn v1 .yarnrc line
+// (key "value", with the key optionally quoted)
+func parseNpmrcLine(line string, lineStart int) (npmrcSetting, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';' {
+		return npmrcSetting{}, false
+	}
+	setting := npmrcSetting{offset: lineStart + strings.Index(line, trimmed)}
+
+	var rest string
+	if trimmed[0] == '"' {
+		end := strings.IndexByte(trimmed[1:], '"')
+		if end == -1 {
+			return npmrcSetting{}, false
+		}
+		setting.key, rest = trimmed[1:end+1], trimmed[end+2:]
+		setting.offset++
+	} else {
+		end := strings.IndexAny(trimmed, "= \t")
+		if end == -1 {
+			return npmrcSetting{}, false
+		}
+		setting.key, rest = trimmed[:end], trimmed[end:]
+	}
+
+	rest = strings.TrimSpace(rest)
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+	setting.value = strings.Trim(rest, `"'`)
+	return setting, setting.key != ""
+}
+
+// registryHost returns a registry URL without its scheme and trailing slash,
+// as npm matches registries to //host/path/: settings
+func registryHost(registry string) string {
+	if i := strings.Index(registry, "//"); i != -1 {
+		registry = registry[i:]
+	}
+	return strings.TrimSuffix(registry, "/")
+}
@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// nameFieldRegex matches the name field of a package.json up to its value
+var nameFieldRegex = regexp.MustCompile(`"name"\s*:\s*"`)
+
+// packageJSONNamePackages returns the package's own name, as an internal
+// package name leaking from a public package.json is the classic dependency
+// confusion case. Only JSON that looks like a manifest (with a version,
+// dependencies or a publish registry) is trusted to name a package. A
+// publishConfig.registry other than the public registry marks the package,
+// and its scope, as private
+func (r *Runner) packageJSONNamePackages(pkg *PackageJSON, content string) []Package {
+	if pkg.Name == "" {
+		return nil
+	}
+	registry := pkg.PublishConfig.Registry
+	if pkg.Version == "" && len(pkg.Dependencies)+len(pkg.DevDependencies)+len(pkg.PeerDependencies) == 0 && registry == "" {
+		return nil
+	}
+
+	offset := -1
+	if loc := nameFieldRegex.FindStringIndex(content); loc != nil && strings.HasPrefix(content[loc[1]:], pkg.Name+`"`) {
+		offset = loc[1]
+	}
+	packages := []Package{r.createPackageFromName(pkg.Name, "package-json-name", offset)}
+
+	if registry != "" && !r.isPublicRegistry(registry) {
+		registryOffset := indexQuoted(content, registry)
+		packages = append(packages, r.createPackageFromName(pkg.Name, "publish-config", registryOffset))
+
+		if name, err := ParsePackageName(pkg.Name); err == nil && name.IsScoped() {
+			scope := r.createScopeFromName(name.Namespace, "publish-config", registryOffset)
+			scope.ScopeRegistry = redactSecrets(registry)
+			packages = append(packages, scope)
+		}
+	}
+
+	return packages
+}
+
+// packageJSONOverridePackages returns the packages whose versions are pinned
+// by npm overrides, yarn resolutions and pnpm overrides, and the packages
+// patched by pnpm patchedDependencies
+func (r *Runner) packageJSONOverridePackages(pkg *PackageJSON, content string) []Package {
+	packages := r.npmOverridePackages(pkg.Overrides, content)
+
+	for key, version := range pkg.Resolutions {
+		offset := indexQuoted(content, key)
+		for _, name := range resolutionNames(key) {
+			packages = append(packages, r.createPackageFromName(name, "package-json-override", indexFrom(key, name, offset)))
+		}
+		packages = append(packages, r.npmAliasPackages(version, "package-json-override", content)...)
+	}
+
+	for key, version := range pkg.Pnpm.Overrides {
+		// keys such as lodash@<4.17.21 or foo@1>bar, as in pnpm-lock.yaml
+		offset := indexQuoted(content, key)
+		for _, part := range strings.Split(key, ">") {
+			if name, ok := NormalizeSpecifier(part); ok {
+				packages = append(packages, r.createPackageFromName(name, "package-json-override", indexFrom(key, name, offset)))
+			}
+		}
+		packages = append(packages, r.npmAliasPackages(version, "package-json-override", content)...)
+	}
+
+	for key := range pkg.Pnpm.PatchedDependencies {
+		if name, ok := NormalizeSpecifier(key); ok {
+			packages = append(packages, r.createPackageFromName(name, "package-json-override", indexQuoted(content, key)))
+		}
+	}
+
+	return packages
+}
+
+// npmOverridePackages returns the packages of npm overrides, which map
+// package specifiers to a version, a $reference to a dependency's version
+// or to the overrides of the package's own dependencies
+func (r *Runner) npmOverridePackages(overrides map[string]json.RawMessage, content string) []Package {
+	var packages []Package
+
+	for key, value := range overrides {
+		// "." overrides the version of the enclosing package
+		if key != "." {
+			if name, ok := NormalizeSpecifier(key); ok {
+				packages = append(packages, r.createPackageFromName(name, "package-json-override", indexFrom(key, name, indexQuoted(content, key))))
+			}
+		}
+
+		var nested map[string]json.RawMessage
+		var version string
+		if json.Unmarshal(value, &nested) == nil {
+			packages = append(packages, r.npmOverridePackages(nested, content)...)
+		} else if json.Unmarshal(value, &version) == nil {
+			packages = append(packages, r.npmAliasPackages(version, "package-json-override", content)...)
+		}
+	}
+
+	return packages
+}
+
+// npmAliasPackages returns the real package of a version such as
+// "npm:real@^1.0.0", which installs real under another name
+func (r *Runner) npmAliasPackages(version, method, content string) []Package {
+	if real, ok := NormalizeSpecifier(version); ok && strings.HasPrefix(version, "npm:") {
+		return []Package{r.createPackageFromName(real, method, indexQuoted(content, version))}
+	}
+	return nil
+}
+
+// packageJSONWorkspacePackages returns the packages that workspace paths
+// without globs (e.g. packages/shared-ui) are likely to be named after.
+// Workspace packages are internal by nature, though the directory name is
+// only a guess at the package name
+func (r *Runner) packageJSONWorkspacePackages(pkg *PackageJSON, content string) []Package {
+	workspaces := jsonStrings(pkg.Workspaces)
+	if workspaces == nil {
+		// yarn's {"packages": [...], "nohoist": [...]} form
+		var yarnWorkspaces struct {
+			Packages []string `json:"packages"`
+		}
+		if json.Unmarshal(pkg.Workspaces, &yarnWorkspaces) == nil {
+			workspaces = yarnWorkspaces.Packages
+		}
+	}
+
+	var packages []Package
+	for _, workspace := range workspaces {
+		if strings.ContainsAny(workspace, "*?[]{}!") {
+			continue
+		}
+		name := path.Base(strings.TrimSuffix(workspace, "/"))
+		if name == "." || name == ".." || name == "/" || !r.looksLikePackageName(name) {
+			continue
+		}
+		packages = append(packages, r.createPackageFromName(name, "package-json-workspace", indexFrom(workspace, name, indexQuoted(content, workspace))))
+	}
+	return packages
+}
+
+// resolutionNames returns the packages named by a yarn resolutions key,
+// which is a path of packages such as "**/lodash", "webpack/terser" or
+// "@scope/a@1.0.0/**/@scope/b"
+func resolutionNames(key string) []string {
+	var names []string
+
+	parts := strings.Split(key, "/")
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		if part == "**" || part == "*" {
+			continue
+		}
+		if strings.HasPrefix(part, "@") && i+1 < len(parts) {
+			i++
+			part += "/" + parts[i]
+		}
+		if name, ok := NormalizeSpecifier(part); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// jsonStrings decodes a JSON array of strings, returning nil for anything
+// else (e.g. "bundleDependencies": true)
+func jsonStrings(raw json.RawMessage) []string {
+	var values []string
+	if len(raw) == 0 || json.Unmarshal(raw, &values) != nil {
+		return nil
+	}
+	return values
+}
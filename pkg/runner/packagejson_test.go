@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageJSONName(t *testing.T) {
+	tests := []struct {
+		content string
+		found   bool
+	}{
+		{`{"name": "internal-dashboard", "version": "1.0.0"}`, true},
+		{`{"name": "internal-dashboard", "dependencies": {"react": "^18.0.0"}}`, true},
+		{`{"name": "internal-dashboard", "publishConfig": {"registry": "https://npm.company.com/"}}`, true},
+		{`{"name": "internal-dashboard", "short_name": "Dashboard"}`, false},
+	}
+
+	runner := NewRunner()
+	for _, tt := range tests {
+		packages := runner.extractPackages("package.json", "", []byte(tt.content))
+
+		pkg, ok := findPackage(packages, "internal-dashboard")
+		if ok != tt.found {
+			t.Errorf("%s: expected found %v, got %v", tt.content, tt.found, packages)
+			continue
+		}
+		if ok && (pkg.Evidence[0].Method != "package-json-name" || pkg.Evidence[0].Column != 11) {
+			t.Errorf("%s: expected package-json-name at column 11, got %+v", tt.content, pkg.Evidence[0])
+		}
+	}
+}
+
+func TestPublishConfigRegistry(t *testing.T) {
+	content := `{"name": "@company/ui", "version": "1.0.0", "publishConfig": {"registry": "https://npm.company.com/"}}`
+
+	runner := NewRunner()
+	packages := runner.extractPackages("package.json", "", []byte(content))
+
+	var methods []string
+	for _, pkg := range packages {
+		if pkg.FullName() == "@company/ui" {
+			methods = append(methods, pkg.Methods()...)
+		}
+	}
+	if !reflect.DeepEqual(methods, []string{"package-json-name", "publish-config"}) {
+		t.Errorf("expected @company/ui by name and publish config, got %v", packages)
+	}
+	scope, ok := findPackage(packages, "@company")
+	if !ok || !scope.IsScopeOnly() || scope.ScopeRegistry != "https://npm.company.com/" {
+		t.Errorf("expected @company routed to https://npm.company.com/, got %v", packages)
+	}
+
+	public := `{"name": "@company/ui", "version": "1.0.0", "publishConfig": {"registry": "https://registry.npmjs.org/"}}`
+	packages = runner.extractPackages("package.json", "", []byte(public))
+	if _, ok := findPackage(packages, "@company"); ok {
+		t.Errorf("expected no scope finding for the public registry, got %v", packages)
+	}
+}
+
+func TestBundleDependenciesTrue(t *testing.T) {
+	content := `{"dependencies": {"left-pad": "1.3.0"}, "bundleDependencies": true, "workspaces": ["packages/*"]}`
+
+	runner := NewRunner()
+	packages := runner.extractPackages("package.json", "", []byte(content))
+
+	if _, ok := findPackage(packages, "left-pad"); !ok {
+		t.Errorf("expected left-pad to be detected, got %v", packages)
+	}
+}
+
+func TestResolutionNames(t *testing.T) {
+	tests := []struct {
+		key   string
+		names []string
+	}{
+		{"lodash", []string{"lodash"}},
+		{"**/lodash", []string{"lodash"}},
+		{"webpack/terser", []string{"webpack", "terser"}},
+		{"@scope/a@1.0.0/**/@scope/b", []string{"@scope/a", "@scope/b"}},
+	}
+
+	for _, tt := range tests {
+		if names := resolutionNames(tt.key); !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("%q: expected %v, got %v", tt.key, tt.names, names)
+		}
+	}
+}
@@ -11,7 +11,13 @@ var expectedPackages = map[string][]string{
 		"express", "@types/node", "lodash", "@company/private-pkg", "unclaimed-package-123",
 		"react", "vulnerable-lib", "webpack", "babel-loader", "@babel/core", "eslint",
 		"test-helper-unclaimed", "react-dom", "peer-dependency-missing", "fsevents",
-		"optional-missing-pkg",
+		"optional-missing-pkg", "example-app",
+	},
+	"testdata/config/monorepo/package.json": {
+		"@company/platform", "@company", "company-eslint-config", "react", "react-dom",
+		"company-analytics-bridge", "company-legacy-shim", "semver", "eslint-plugin-company",
+		"company-rule-utils", "request", "@cypress/request", "minimist", "webpack",
+		"company-webpack-helpers", "foo-loader", "company-transform", "company-patched-dep",
 	},
 	"testdata/config/package-lock.json": {
 		"express", "transitive-unclaimed", "body-parser", "hidden-dependency",
@@ -21,6 +27,25 @@ var expectedPackages = map[string][]string{
 		"@babel/core", "@babel/generator", "yarn-specific-missing", "express",
 		"body-parser", "cookie", "missing-yarn-dep", "unclaimed-in-yarn",
 	},
+	"testdata/config/pnpm-lock.yaml": {
+		"@company/private-pkg", "express", "react", "unclaimed-pnpm-tool", "@company/shared-utils",
+		"missing-workspace-dep", "body-parser", "hidden-pnpm-dependency", "bytes", "supports-color",
+		"string-width", "vulnerable-lib",
+	},
+	"testdata/config/berry/yarn.lock": {
+		"@babel/core", "@babel/code-frame", "debug", "missing-berry-dep", "supports-color",
+		"@company/internal-ui", "local-portal-lib", "lodash", "resolve", "string-width-cjs",
+		"string-width",
+	},
+	"testdata/config/.yarnrc.yml": {
+		"@company", "@company-labs", "legacy-widget", "unclaimed-widget-peer",
+	},
+	"testdata/config/.npmrc": {
+		"@company", "@company-tools",
+	},
+	"testdata/config/.yarnrc": {
+		"@acme",
+	},
 	"testdata/javascript/mixed-imports.js": {
 		"express", "utility-helper", "lodash", "moment", "react", "helper-utils",
 		"complex-package", "side-effect-import", "dynamic-import-pkg", "lazy-loaded-module",
@@ -75,6 +100,12 @@ var expectedPackages = map[string][]string{
 		"deployment-tools", "missing-deploy-cli", "production-deploy-helper", "deploy-to-prod",
 		"monitoring-agent", "missing-cleanup-tool",
 	},
+	"testdata/build/.vite/manifest.json": {
+		"@company/design-tokens", "company-charts", "react-dom",
+	},
+	"testdata/build/meta.json": {
+		"react", "@company/auth-client", "company-http-retry", "company-telemetry-shim",
+	},
 	"testdata/docs/README.md": {
 		"express", "react", "lodash", "webpack", "babel-loader", "eslint",
 		"missing-global-tool", "@types/node", "@babel/core", "missing-pnpm-package",
@@ -83,6 +114,9 @@ var expectedPackages = map[string][]string{
 		"missing-package-in-docs", "@company/internal-tool", "vulnerable-old-version",
 		"unclaimed-helper-lib", "missing-react-component",
 	},
+	"testdata/spa/inline.js": {
+		"company-cart-core", "company-analytics-sdk", "lodash.debounce", "@company/checkout-widgets",
+	},
 	"testdata/spa/app.js.map": {
 		"react", "@babel/core", "lodash", "@types/node", "missing-spa-package", 
 		"express", "unclaimed-helper", "moment", "vulnerable-spa-lib",
@@ -128,11 +162,11 @@ func TestPackageDetection(t *testing.T) {
 			}
 
 			runner := NewRunner()
-			packages := runner.extractPackages(testFile, string(content))
+			packages := runner.extractPackages(testFile, "", content)
 			detectedPackages := make(map[string]bool)
 
 			for _, pkg := range packages {
-				detectedPackages[pkg.Name] = true
+				detectedPackages[pkg.FullName()] = true
 			}
 
 			t.Logf("File: %s", testFile)
@@ -182,6 +216,6 @@ func BenchmarkEnhancedExtraction(b *testing.B) {
 	runner := NewRunner()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		runner.extractPackages("test.js", testContent)
+		runner.extractPackages("test.js", "", []byte(testContent))
 	}
 }
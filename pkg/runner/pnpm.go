@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pnpmDependencyFields are the fields that map package names to versions in
+// importers, top level dependencies and package entries
+var pnpmDependencyFields = map[string]bool{
+	"specifiers":           true,
+	"dependencies":         true,
+	"devDependencies":      true,
+	"optionalDependencies": true,
+	"peerDependencies":     true,
+}
+
+func (r *Runner) isPnpmLockFile(url string) bool {
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		url = url[:i]
+	}
+	return strings.HasSuffix(strings.ToLower(url), "pnpm-lock.yaml")
+}
+
+// extractFromPnpmLock finds the packages of a pnpm-lock.yaml file. It reads
+// the lockfile line by line rather than as YAML, which works for lockfile
+// versions 5.x (/name/1.0.0 package keys), 6.x (/name@1.0.0(peer@2.0.0))
+// and 9.x (name@1.0.0, with dependencies under snapshots) alike
+func (r *Runner) extractFromPnpmLock(content string) []Package {
+	var packages []Package
+	major := 0 // major lockfile version, 0 until known
+
+	scanYAML(content, func(line yamlLine) {
+		if line.depth == 0 && line.key == "lockfileVersion" {
+			major, _ = strconv.Atoi(strings.SplitN(line.value, ".", 2)[0])
+			return
+		}
+		packages = append(packages, r.pnpmLinePackages(line, major)...)
+	})
+
+	return packages
+}
+
+// pnpmLinePackages returns the packages named by a lockfile line, judging by
+// where in the lockfile it is
+func (r *Runner) pnpmLinePackages(line yamlLine, major int) []Package {
+	path := line.path
+	depth := len(path) - 1
+	if depth == 0 {
+		return nil
+	}
+
+	var names []string
+	var valueNames []string // names found in the value, e.g. npm aliases
+	method := "pnpm-lock"
+
+	switch section := path[0]; {
+	case pnpmDependencyFields[section]:
+		// v5 and v6 lockfiles without workspaces list the root project's
+		// dependencies at the top level
+		switch {
+		case depth == 1:
+			names = append(names, line.key)
+			valueNames = append(valueNames, pnpmAliasName(line.value, major)...)
+		case depth == 2 && line.key == "version":
+			valueNames = append(valueNames, pnpmAliasName(line.value, major)...)
+		}
+
+	case section == "importers":
+		switch {
+		case depth == 3 && pnpmDependencyFields[path[2]]:
+			names = append(names, line.key)
+			valueNames = append(valueNames, pnpmAliasName(line.value, major)...)
+		case depth == 4 && pnpmDependencyFields[path[2]] && line.key == "version":
+			valueNames = append(valueNames, pnpmAliasName(line.value, major)...)
+		}
+
+	case section == "packages" || section == "snapshots":
+		switch {
+		case depth == 1:
+			if name, ok := pnpmPackageName(line.key, major); ok {
+				names = append(names, name)
+			}
+		case depth == 2 && line.key == "name":
+			// packages from tarballs, git or local directories name themselves
+			valueNames = append(valueNames, line.value)
+		case depth == 3 && (pnpmDependencyFields[path[2]] || path[2] == "transitivePeerDependencies"):
+			method = "pnpm-lock-dependency"
+			names = append(names, line.key)
+			valueNames = append(valueNames, pnpmAliasName(line.value, major)...)
+		}
+
+	case section == "time":
+		if depth == 1 {
+			if name, ok := pnpmPackageName(line.key, major); ok {
+				names = append(names, name)
+			}
+		}
+
+	case section == "overrides" || section == "patchedDependencies":
+		// keys such as lodash@<4.17.21, foo@1>bar or lodash@4.17.21
+		if depth == 1 {
+			for _, part := range strings.Split(line.key, ">") {
+				if name, ok := NormalizeSpecifier(part); ok {
+					names = append(names, name)
+				}
+			}
+		}
+
+	case section == "catalogs":
+		if depth == 2 {
+			names = append(names, line.key)
+		}
+	}
+
+	var packages []Package
+	for _, name := range names {
+		packages = append(packages, r.createPackageFromName(name, method, line.offset))
+	}
+	for _, name := range valueNames {
+		packages = append(packages, r.createPackageFromName(name, method, indexFrom(line.value, name, line.valueOffset)))
+	}
+	return packages
+}
+
+// pnpmPackageName returns the name of the package in a packages, snapshots or
+// time key. Keys of packages that don't come from a registry, such as
+// github.com/user/repo/1a2b3c in v5 and v6 lockfiles, are skipped since
+// those packages are named by their name field instead
+func pnpmPackageName(key string, major int) (string, bool) {
+	if i := strings.IndexByte(key, '('); i != -1 {
+		key = key[:i]
+	}
+
+	if major != 0 && major < 9 {
+		if !strings.HasPrefix(key, "/") {
+			return "", false
+		}
+		key = key[1:]
+	} else {
+		key = strings.TrimPrefix(key, "/")
+	}
+
+	if major != 0 && major < 6 {
+		// name/1.0.0 or @scope/name/1.0.0_peer@2.0.0
+		parts := strings.Split(key, "/")
+		if strings.HasPrefix(key, "@") && len(parts) > 2 {
+			return parts[0] + "/" + parts[1], true
+		}
+		if !strings.HasPrefix(key, "@") && len(parts) > 1 {
+			return parts[0], true
+		}
+		return "", false
+	}
+
+	return NormalizeSpecifier(key)
+}
+
+// pnpmAliasName returns the package a dependency version refers to when it
+// is an npm alias, e.g. string-width-cjs: string-width@4.2.3 (/string-width@4.2.3
+// in v6 and /string-width/4.2.3 in v5). Plain versions return nothing
+func pnpmAliasName(value string, major int) []string {
+	version := value
+	if i := strings.IndexByte(version, '('); i != -1 {
+		version = version[:i]
+	}
+	if !strings.HasPrefix(version, "/") && strings.IndexByte(version, '@') < 1 {
+		return nil
+	}
+	if name, ok := pnpmPackageName(version, major); ok {
+		return []string{name}
+	}
+	return nil
+}
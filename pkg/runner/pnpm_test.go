@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExtractFromPnpmLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		lockfile string
+		expected []string
+	}{
+		{
+			name: "v5",
+			lockfile: `lockfileVersion: 5.4
+
+specifiers:
+  '@babel/core': ^7.20.0
+  lodash: ^4.17.21
+
+dependencies:
+  '@babel/core': 7.20.0_supports-color@9.0.0
+  lodash: 4.17.21
+
+packages:
+
+  /@babel/core/7.20.0_supports-color@9.0.0:
+    resolution: {integrity: sha512-AAAA}
+    dependencies:
+      '@babel/code-frame': 7.18.6
+      debug: 4.3.4_supports-color@9.0.0
+    dev: false
+
+  /lodash/4.17.21:
+    resolution: {integrity: sha512-BBBB}
+    dev: false
+
+  github.com/company/internal-fork/1a2b3c:
+    resolution: {tarball: https://codeload.github.com/company/internal-fork/tar.gz/1a2b3c}
+    name: internal-fork
+    version: 1.0.0
+`,
+			expected: []string{"@babel/code-frame", "@babel/core", "debug", "internal-fork", "lodash"},
+		},
+		{
+			name: "v6",
+			lockfile: `lockfileVersion: '6.0'
+
+dependencies:
+  '@babel/core':
+    specifier: ^7.20.0
+    version: 7.20.0(supports-color@9.0.0)
+  my-lodash:
+    specifier: npm:lodash@^4.17.21
+    version: /lodash@4.17.21
+
+packages:
+
+  /@babel/core@7.20.0(supports-color@9.0.0):
+    resolution: {integrity: sha512-AAAA}
+    dependencies:
+      debug: 4.3.4(supports-color@9.0.0)
+    transitivePeerDependencies:
+      - supports-color
+    dev: false
+
+  /lodash@4.17.21:
+    resolution: {integrity: sha512-BBBB}
+    dev: false
+`,
+			expected: []string{"@babel/core", "debug", "lodash", "my-lodash", "supports-color"},
+		},
+		{
+			name: "v9",
+			lockfile: `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      internal-lib:
+        specifier: file:../internal-lib
+        version: file:../internal-lib
+
+packages:
+
+  internal-lib@file:../internal-lib:
+    resolution: {directory: ../internal-lib, type: directory}
+
+  '@scope/pkg@2.0.0':
+    resolution: {integrity: sha512-AAAA}
+
+snapshots:
+
+  '@scope/pkg@2.0.0(react@18.2.0)':
+    dependencies:
+      react: 18.2.0
+`,
+			expected: []string{"@scope/pkg", "internal-lib", "react"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewRunner()
+			found := make(map[string]bool)
+			for _, pkg := range runner.extractFromPnpmLock(tt.lockfile) {
+				found[pkg.FullName()] = true
+			}
+
+			names := getKeys(found)
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestPnpmLockEvidence(t *testing.T) {
+	content := "lockfileVersion: '9.0'\n\nsnapshots:\n\n  express@4.18.2:\n    dependencies:\n      string-width-cjs: string-width@4.2.3\n"
+
+	runner := NewRunner()
+	packages := runner.extractPackages("https://example.com/pnpm-lock.yaml?raw=1", "", []byte(content))
+
+	tests := []struct {
+		pkg    string
+		method string
+		line   int
+		column int
+	}{
+		{"express", "pnpm-lock", 5, 3},
+		{"string-width-cjs", "pnpm-lock-dependency", 7, 7},
+		{"string-width", "pnpm-lock-dependency", 7, 25},
+	}
+
+	for _, tt := range tests {
+		pkg, ok := findPackage(packages, tt.pkg)
+		if !ok {
+			t.Errorf("%s not detected in %v", tt.pkg, packages)
+			continue
+		}
+		e := pkg.Evidence[0]
+		if e.Method != tt.method || e.Line != tt.line || e.Column != tt.column {
+			t.Errorf("%s: expected %s at %d:%d, got %s at %d:%d", tt.pkg, tt.method, tt.line, tt.column, e.Method, e.Line, e.Column)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/root4loot/goutils/log"
+)
+
+// ClaimStatus describes what the registry told us about a package
+type ClaimStatus int
+
+const (
+	StatusUnknown     ClaimStatus = iota // lookup failed or returned an unexpected response
+	StatusClaimed                        // package exists on the registry
+	StatusUnclaimed                      // registry returned 404
+	StatusRateLimited                    // registry returned 429
+)
+
+// String returns a human readable representation of the claim status
+func (s ClaimStatus) String() string {
+	switch s {
+	case StatusClaimed:
+		return "claimed"
+	case StatusUnclaimed:
+		return "unclaimed"
+	case StatusRateLimited:
+		return "rate-limited"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s ClaimStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *ClaimStatus) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "claimed":
+		*s = StatusClaimed
+	case "unclaimed":
+		*s = StatusUnclaimed
+	case "rate-limited":
+		*s = StatusRateLimited
+	default:
+		*s = StatusUnknown
+	}
+	return nil
+}
+
+// RegistryRole describes what a registry is consulted for
+type RegistryRole int
+
+const (
+	RolePublic   RegistryRole = iota // public registry, checked for claimability
+	RoleInternal                     // internal registry, checked to confirm a package is internal
+)
+
+// String returns the name of the registry role
+func (role RegistryRole) String() string {
+	if role == RoleInternal {
+		return "internal"
+	}
+	return "public"
+}
+
+// Registry is an npm compatible registry such as registry.npmjs.com, a mirror,
+// or a private registry like Verdaccio
+type Registry struct {
+	URL   string       // registry base URL
+	Token string       // auth token sent as a bearer token (optional)
+	Role  RegistryRole // what the registry is consulted for
+}
+
+// ParseRegistry parses a registry definition of the form "<url> [public|internal] [token]"
+func ParseRegistry(definition string) (Registry, error) {
+	fields := strings.Fields(definition)
+	if len(fields) == 0 || len(fields) > 3 {
+		return Registry{}, fmt.Errorf("invalid registry definition %q: expected <url> [public|internal] [token]", definition)
+	}
+
+	reg := Registry{URL: fields[0], Role: RolePublic}
+	if !strings.HasPrefix(reg.URL, "http://") && !strings.HasPrefix(reg.URL, "https://") {
+		return Registry{}, fmt.Errorf("invalid registry URL %q: missing http(s) scheme", reg.URL)
+	}
+
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "public":
+			reg.Role = RolePublic
+		case "internal":
+			reg.Role = RoleInternal
+		default:
+			return Registry{}, fmt.Errorf("invalid registry role %q: expected public or internal", fields[1])
+		}
+	}
+
+	if len(fields) > 2 {
+		reg.Token = fields[2]
+	}
+
+	return reg, nil
+}
+
+// IsClaimed reports whether the package was found on the registry
+func (p Package) IsClaimed() bool {
+	return p.Status == StatusClaimed
+}
+
+// IsScopeUnclaimed reports whether the registry confirmed that the package's
+// scope is not registered as an organization or user
+func (p Package) IsScopeUnclaimed() bool {
+	return p.ScopeStatus == StatusUnclaimed
+}
+
+// IsInternal reports whether an internal registry confirmed the package exists
+func (p Package) IsInternal() bool {
+	return p.InternalStatus == StatusClaimed
+}
+
+// IsUnclaimed reports whether the registry confirmed the package does not exist
+func (p Package) IsUnclaimed() bool {
+	return p.Status == StatusUnclaimed
+}
+
+// claimStatusFromCode maps a registry HTTP status code to a claim status
+func claimStatusFromCode(code int) ClaimStatus {
+	switch {
+	case code == http.StatusOK:
+		return StatusClaimed
+	case code == http.StatusNotFound:
+		return StatusUnclaimed
+	case code == http.StatusTooManyRequests:
+		return StatusRateLimited
+	default:
+		return StatusUnknown
+	}
+}
+
+// claimPackage fills in the claim status of the package from the public
+// registries and, if any are configured, the internal registries
+func (r *Runner) claimPackage(ctx context.Context, pkg *Package) {
+	if pkg.IsRegistryOnly() {
+		return // nothing to claim on the public registry
+	}
+	if pkg.IsScopeOnly() {
+		// a scope finding is claimed if the scope is taken on the public registry
+		pkg.Status, pkg.RegistryStatus, pkg.Registry, pkg.RegistryError = r.queryRegistries(RolePublic, func(reg Registry) (ClaimStatus, int, error) {
+			return r.checkScopeClaim(ctx, reg, pkg.Namespace)
+		})
+		pkg.ScopeStatus = pkg.Status
+		return
+	}
+
+	name := pkg.FullName()
+
+	pkg.Status, pkg.RegistryStatus, pkg.Registry, pkg.RegistryError = r.queryRegistries(RolePublic, func(reg Registry) (ClaimStatus, int, error) {
+		return r.checkPackageClaim(ctx, reg, name)
+	})
+
+	if pkg.IsScoped() {
+		pkg.ScopeStatus, _, _, _ = r.queryRegistries(RolePublic, func(reg Registry) (ClaimStatus, int, error) {
+			return r.checkScopeClaim(ctx, reg, pkg.Namespace)
+		})
+	}
+
+	if r.hasRegistryRole(RoleInternal) {
+		pkg.InternalStatus, _, pkg.InternalRegistry, _ = r.queryRegistries(RoleInternal, func(reg Registry) (ClaimStatus, int, error) {
+			return r.checkPackageClaim(ctx, reg, name)
+		})
+	}
+}
+
+// queryRegistries runs lookup against every registry with the given role and
+// returns the combined status along with the registry that decided it. A
+// package is claimed if any registry has it, and unclaimed only if every
+// registry answered 404
+func (r *Runner) queryRegistries(role RegistryRole, lookup func(Registry) (ClaimStatus, int, error)) (status ClaimStatus, code int, registry string, err error) {
+	failed := false
+
+	for _, reg := range r.registries(role) {
+		s, c, e := lookup(reg)
+		switch s {
+		case StatusClaimed:
+			return s, c, reg.URL, nil
+		case StatusUnclaimed:
+			if registry == "" {
+				status, code, registry, err = s, c, reg.URL, nil
+			}
+		default:
+			if !failed {
+				status, code, registry, err = s, c, reg.URL, e
+				failed = true
+			}
+		}
+	}
+
+	return
+}
+
+// registries returns the configured registries with the given role. Without
+// a public registry, such as when only internal ones are configured, the
+// default public registry is used
+func (r *Runner) registries(role RegistryRole) (registries []Registry) {
+	for _, reg := range r.Options.Registries {
+		if reg.Role == role {
+			reg.URL = strings.TrimSuffix(reg.URL, "/")
+			registries = append(registries, reg)
+		}
+	}
+	if role == RolePublic && len(registries) == 0 {
+		return DefaultOptions().Registries
+	}
+	return
+}
+
+// hasRegistryRole reports whether any registry with the given role is configured
+func (r *Runner) hasRegistryRole(role RegistryRole) bool {
+	return len(r.registries(role)) > 0
+}
+
+// checkPackageClaim returns the claim status of the package on the given
+// registry, consulting the shared registry cache before asking the registry
+func (r *Runner) checkPackageClaim(ctx context.Context, reg Registry, packageName string) (ClaimStatus, int, error) {
+	return r.cache.lookup(reg.URL+" "+packageName, func() (ClaimStatus, int, error) {
+		return r.lookupPackage(ctx, reg, packageName)
+	})
+}
+
+// checkScopeClaim returns the claim status of the scope (e.g. @company) on the
+// given registry, consulting the shared registry cache before asking the registry
+func (r *Runner) checkScopeClaim(ctx context.Context, reg Registry, scope string) (ClaimStatus, int, error) {
+	return r.cache.lookup(reg.URL+" scope:"+scope, func() (ClaimStatus, int, error) {
+		return r.lookupScope(ctx, reg, scope)
+	})
+}
+
+// lookupPackage asks the registry whether the package exists
+func (r *Runner) lookupPackage(ctx context.Context, reg Registry, packageName string) (ClaimStatus, int, error) {
+	url := fmt.Sprintf("%s/%s", reg.URL, strings.Replace(packageName, "/", "%2f", 1))
+	return r.registryRequest(ctx, reg, http.MethodHead, url, packageName)
+}
+
+// lookupScope asks the registry whether the scope is registered as an
+// organization or, failing that, as a user
+func (r *Runner) lookupScope(ctx context.Context, reg Registry, scope string) (ClaimStatus, int, error) {
+	name := strings.TrimPrefix(scope, "@")
+
+	url := fmt.Sprintf("%s/-/org/%s/package", reg.URL, name)
+	status, code, err := r.registryRequest(ctx, reg, http.MethodGet, url, scope)
+	if status != StatusUnclaimed {
+		return status, code, err
+	}
+
+	url = fmt.Sprintf("%s/-/user/%s/package", reg.URL, name)
+	return r.registryRequest(ctx, reg, http.MethodGet, url, scope)
+}
+
+// registryRequest requests the given registry URL and maps the response to a
+// claim status. subject is the package or scope being checked
+func (r *Runner) registryRequest(ctx context.Context, reg Registry, method, url, subject string) (ClaimStatus, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return StatusUnknown, 0, err
+	}
+
+	if r.Options.UserAgent != "" {
+		req.Header.Add("User-Agent", r.Options.UserAgent)
+	}
+	if reg.Token != "" {
+		req.Header.Add("Authorization", "Bearer "+reg.Token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		log.Warnf("Error: %v", err)
+		return StatusUnknown, 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	status := claimStatusFromCode(resp.StatusCode)
+	if status == StatusUnknown {
+		err = fmt.Errorf("unexpected response from %s for %s: %s", reg.URL, subject, resp.Status)
+		log.Warnf("%v", err)
+	} else if status == StatusRateLimited {
+		err = fmt.Errorf("rate limited by %s while checking %s", reg.URL, subject)
+		log.Warnf("%v", err)
+	}
+
+	return status, resp.StatusCode, err
+}
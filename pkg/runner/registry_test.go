@@ -0,0 +1,265 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newStubServer(t *testing.T, routes map[string]int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		code, ok := routes[req.URL.EscapedPath()]
+		if !ok {
+			code = http.StatusNotFound
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newStubRegistry(t *testing.T, routes map[string]int) *Runner {
+	t.Helper()
+
+	runner := NewRunner()
+	runner.Options.Registries = []Registry{{URL: newStubServer(t, routes).URL, Role: RolePublic}}
+	return runner
+}
+
+func TestClaimStatusFromCode(t *testing.T) {
+	tests := []struct {
+		code     int
+		expected ClaimStatus
+	}{
+		{http.StatusOK, StatusClaimed},
+		{http.StatusNotFound, StatusUnclaimed},
+		{http.StatusTooManyRequests, StatusRateLimited},
+		{http.StatusMovedPermanently, StatusUnknown},
+		{http.StatusForbidden, StatusUnknown},
+		{http.StatusInternalServerError, StatusUnknown},
+		{http.StatusBadGateway, StatusUnknown},
+		{0, StatusUnknown}, // no response
+	}
+
+	for _, tt := range tests {
+		if status := claimStatusFromCode(tt.code); status != tt.expected {
+			t.Errorf("%d: expected %v, got %v", tt.code, tt.expected, status)
+		}
+	}
+}
+
+func TestClaimStatusFailedLookup(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	runner := NewRunner()
+	status, code, err := runner.lookupPackage(context.Background(), Registry{URL: server.URL, Role: RolePublic}, "react")
+	if status != StatusUnknown || code != 0 || err == nil {
+		t.Errorf("expected unknown status with an error, got %v, %d, %v", status, code, err)
+	}
+}
+
+func TestClaimStatusText(t *testing.T) {
+	for _, status := range []ClaimStatus{StatusUnknown, StatusClaimed, StatusUnclaimed, StatusRateLimited} {
+		text, err := status.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded ClaimStatus
+		if err := decoded.UnmarshalText(text); err != nil || decoded != status {
+			t.Errorf("%v: expected round trip through %q, got %v (error: %v)", status, text, decoded, err)
+		}
+	}
+
+	decoded := StatusClaimed
+	if err := decoded.UnmarshalText([]byte("bogus")); err != nil || decoded != StatusUnknown {
+		t.Errorf("expected unrecognized text to decode as unknown, got %v (error: %v)", decoded, err)
+	}
+}
+
+func TestCheckPackageClaim(t *testing.T) {
+	runner := newStubRegistry(t, map[string]int{
+		"/react":                http.StatusOK,
+		"/@babel%2fcore":        http.StatusOK,
+		"/rate-limited-package": http.StatusTooManyRequests,
+		"/broken-package":       http.StatusBadGateway,
+	})
+
+	tests := []struct {
+		name     string
+		expected ClaimStatus
+	}{
+		{"react", StatusClaimed},
+		{"@babel/core", StatusClaimed},
+		{"unclaimed-package-123", StatusUnclaimed},
+		{"rate-limited-package", StatusRateLimited},
+		{"broken-package", StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		status, _, err := runner.checkPackageClaim(context.Background(), runner.Options.Registries[0], tt.name)
+		if status != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, status)
+		}
+		if (status == StatusUnknown || status == StatusRateLimited) && err == nil {
+			t.Errorf("%s: expected an error for status %v", tt.name, status)
+		}
+	}
+}
+
+func TestCheckScopeClaim(t *testing.T) {
+	runner := newStubRegistry(t, map[string]int{
+		"/-/org/babel/package":      http.StatusOK,
+		"/-/user/someuser/package":  http.StatusOK,
+		"/-/org/throttled/package":  http.StatusTooManyRequests,
+		"/-/user/throttled/package": http.StatusOK,
+	})
+
+	tests := []struct {
+		scope    string
+		expected ClaimStatus
+	}{
+		{"@babel", StatusClaimed},
+		{"@someuser", StatusClaimed},
+		{"@company", StatusUnclaimed},
+		{"@throttled", StatusRateLimited},
+	}
+
+	for _, tt := range tests {
+		if status, _, _ := runner.checkScopeClaim(context.Background(), runner.Options.Registries[0], tt.scope); status != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.scope, tt.expected, status)
+		}
+	}
+}
+
+func TestClaimPackageMultipleRegistries(t *testing.T) {
+	public := newStubServer(t, map[string]int{"/react": http.StatusOK})
+	mirror := newStubServer(t, map[string]int{"/react": http.StatusOK, "/mirror-only": http.StatusOK, "/flaky-package": http.StatusServiceUnavailable})
+
+	var authorization string
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+		if req.URL.EscapedPath() == "/@company%2fprivate-pkg" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(internal.Close)
+
+	runner := NewRunner()
+	runner.Options.Registries = []Registry{
+		{URL: public.URL, Role: RolePublic},
+		{URL: mirror.URL + "/", Role: RolePublic},
+		{URL: internal.URL, Token: "secret", Role: RoleInternal},
+	}
+
+	tests := []struct {
+		name     string
+		status   ClaimStatus
+		registry string
+		internal ClaimStatus
+	}{
+		{"react", StatusClaimed, public.URL, StatusUnclaimed},
+		{"mirror-only", StatusClaimed, mirror.URL, StatusUnclaimed},
+		{"flaky-package", StatusUnknown, mirror.URL, StatusUnclaimed},
+		{"@company/private-pkg", StatusUnclaimed, public.URL, StatusClaimed},
+	}
+
+	for _, tt := range tests {
+		pkg, _ := ParsePackageName(tt.name)
+		runner.claimPackage(context.Background(), &pkg)
+		if pkg.Status != tt.status || pkg.Registry != tt.registry {
+			t.Errorf("%s: expected %v from %s, got %v from %s", tt.name, tt.status, tt.registry, pkg.Status, pkg.Registry)
+		}
+		if pkg.InternalStatus != tt.internal || pkg.InternalRegistry != internal.URL {
+			t.Errorf("%s: expected internal status %v, got %v from %s", tt.name, tt.internal, pkg.InternalStatus, pkg.InternalRegistry)
+		}
+	}
+
+	if authorization != "Bearer secret" {
+		t.Errorf("expected internal registry to receive the token, got %q", authorization)
+	}
+}
+
+func TestInternalRegistriesKeepPublicDefault(t *testing.T) {
+	runner := NewRunner()
+	runner.Options.Registries = []Registry{{URL: "https://npm.company.com/", Role: RoleInternal}}
+
+	if public := runner.registries(RolePublic); !reflect.DeepEqual(public, DefaultOptions().Registries) {
+		t.Errorf("expected the default public registry, got %v", public)
+	}
+	if internal := runner.registries(RoleInternal); len(internal) != 1 || internal[0].URL != "https://npm.company.com" {
+		t.Errorf("expected the internal registry, got %v", internal)
+	}
+}
+
+func TestParseRegistry(t *testing.T) {
+	tests := []struct {
+		definition string
+		expected   Registry
+		valid      bool
+	}{
+		{"https://registry.npmjs.com", Registry{URL: "https://registry.npmjs.com", Role: RolePublic}, true},
+		{"https://npm.acme.internal internal", Registry{URL: "https://npm.acme.internal", Role: RoleInternal}, true},
+		{"https://npm.acme.internal internal s3cr3t", Registry{URL: "https://npm.acme.internal", Role: RoleInternal, Token: "s3cr3t"}, true},
+		{"npm.acme.internal", Registry{}, false},
+		{"https://npm.acme.internal private", Registry{}, false},
+		{"", Registry{}, false},
+	}
+
+	for _, tt := range tests {
+		reg, err := ParseRegistry(tt.definition)
+		if tt.valid != (err == nil) {
+			t.Errorf("%q: expected valid=%v, got error %v", tt.definition, tt.valid, err)
+			continue
+		}
+		if reg != tt.expected {
+			t.Errorf("%q: expected %+v, got %+v", tt.definition, tt.expected, reg)
+		}
+	}
+}
+
+func TestClaimScopeFinding(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.EscapedPath())
+		if req.URL.EscapedPath() == "/-/org/babel/package" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	runner := NewRunner()
+	runner.Options.Registries = []Registry{{URL: server.URL, Role: RolePublic}}
+
+	tests := []struct {
+		scope  string
+		status ClaimStatus
+	}{
+		{"@babel", StatusClaimed},
+		{"@company", StatusUnclaimed},
+	}
+
+	for _, tt := range tests {
+		paths = nil
+		pkg := Package{Namespace: tt.scope}
+		runner.claimPackage(context.Background(), &pkg)
+		if pkg.Status != tt.status || pkg.ScopeStatus != tt.status {
+			t.Errorf("%s: expected %v, got status %v and scope status %v", tt.scope, tt.status, pkg.Status, pkg.ScopeStatus)
+		}
+		for _, path := range paths {
+			if !strings.HasPrefix(path, "/-/") {
+				t.Errorf("%s: expected only scope lookups, got a request for %s", tt.scope, path)
+			}
+		}
+	}
+}
@@ -4,12 +4,13 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
-	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,17 +27,39 @@ func Init() {
 	log.SetLevel(log.InfoLevel)
 }
 
+// Runner scans URLs and local files for npm packages and checks whether they
+// are claimed.
+//
+// A Runner is created with NewRunner and configured through Options (and
+// Visited) before the first scan. Run, RunContext, ScanFile and ScanDir may
+// then be called any number of times, including concurrently, while a
+// consumer reads Results; all calls share the Options.Concurrency limit, the
+// registry cache and the visited state, so a long-lived Runner can be fed URLs
+// over time. Call Close once every scan has returned to close Results and
+// persist the registry cache and visited state. Scans started after Close
+// return immediately.
 type Runner struct {
-	Options     Options         // options for the runner
-	client      *http.Client    // http client
-	resolver    *CustomResolver // custom DNS resolver
-	Results     chan Result     // channel to receive results
-	Visited     map[string]bool // map of visited urls
-	lastResolver string         // last resolver used for tracking
+	Options      Options         // options for the runner
+	client       *http.Client    // http client
+	resolver     *CustomResolver // custom DNS resolver
+	cache        *registryCache  // registry lookup cache shared across urls
+	setupOnce    sync.Once       // applies the options on first scan
+	sem          chan struct{}   // limits concurrent requests across scans
+	mu           sync.RWMutex    // held for reading by scans and for writing by Close
+	started      bool            // whether a scan has been started
+	closed       bool            // whether Close has been called
+	Results      chan Result     // channel to receive results
+	Visited      VisitedStore    // urls and files that have been scanned
+	claimed      map[string]bool // urls and files being scanned, not yet in Visited
+	claimMu      sync.Mutex      // guards claimed and the move of keys to Visited
+	extractors   []Extractor     // extractors run on every scanned url and file
+	extractorsMu sync.RWMutex    // guards extractors
+	lastResolver string          // last resolver used for tracking
 }
 
 type Result struct {
-	RequestURL string    // url that was requested
+	RequestURL string    // url that was requested, or path of the scanned file
+	Referrers  []string  // urls of the scripts or pages the url was found in, empty for given urls
 	StatusCode int       // status code of the response
 	Packages   []Package // packages found in the response
 	Resolver   string    // DNS resolver used for this request
@@ -44,9 +67,18 @@ type Result struct {
 }
 
 type Package struct {
-	Name      string // package name
-	Namespace string // package namespace
-	Claimed   bool   // whether the package is claimed or not
+	Name             string      // bare package name (e.g. core for @babel/core)
+	Namespace        string      // package scope (e.g. @babel), empty for unscoped packages
+	Status           ClaimStatus // registry claim status of the package
+	RegistryStatus   int         // HTTP status code returned by the registry
+	RegistryError    error       // error returned by the registry lookup, if any
+	Registry         string      // public registry that answered the claim check
+	ScopeStatus      ClaimStatus // registry claim status of the package scope (scoped packages only)
+	InternalStatus   ClaimStatus // claim status on the internal registries, if any are configured
+	InternalRegistry string      // internal registry that answered the internal check
+	ScopeRegistry    string      // private registry the scope is routed to, for scope findings
+	Evidence         []Evidence  // where and how the package was detected
+	Confidence       float64     // confidence (0-1) that the name is a real package dependency
 }
 
 type Results struct {
@@ -54,11 +86,29 @@ type Results struct {
 }
 
 type PackageJSON struct {
-	Dependencies         map[string]string `json:"dependencies"`
-	DevDependencies      map[string]string `json:"devDependencies"`
-	PeerDependencies     map[string]string `json:"peerDependencies"`
-	OptionalDependencies map[string]string `json:"optionalDependencies"`
-	BundledDependencies  []string          `json:"bundledDependencies"`
+	Name                 string                     `json:"name"`
+	Version              string                     `json:"version"`
+	Dependencies         map[string]string          `json:"dependencies"`
+	DevDependencies      map[string]string          `json:"devDependencies"`
+	PeerDependencies     map[string]string          `json:"peerDependencies"`
+	PeerDependenciesMeta map[string]json.RawMessage `json:"peerDependenciesMeta"`
+	OptionalDependencies map[string]string          `json:"optionalDependencies"`
+	BundledDependencies  []string                   `json:"bundledDependencies"`
+	BundleDependencies   json.RawMessage            `json:"bundleDependencies"` // names, or true for all dependencies
+	Workspaces           json.RawMessage            `json:"workspaces"`         // globs, or {"packages": [...]} for yarn
+	Overrides            map[string]json.RawMessage `json:"overrides"`          // npm, versions or nested overrides
+	Resolutions          map[string]string          `json:"resolutions"`        // yarn
+	Pnpm                 PnpmConfig                 `json:"pnpm"`
+	PublishConfig        PublishConfig              `json:"publishConfig"`
+}
+
+type PnpmConfig struct {
+	Overrides           map[string]string `json:"overrides"`
+	PatchedDependencies map[string]string `json:"patchedDependencies"`
+}
+
+type PublishConfig struct {
+	Registry string `json:"registry"`
 }
 
 type PackageLockJSON struct {
@@ -70,17 +120,18 @@ type PackageLockEntry struct {
 }
 
 type SourceMap struct {
-	Sources        []string `json:"sources"`
-	SourcesContent []string `json:"sourcesContent"`
+	Sources        []string           `json:"sources"`
+	SourcesContent []string           `json:"sourcesContent"`
+	Sections       []SourceMapSection `json:"sections"` // index maps only
+}
+
+type SourceMapSection struct {
+	Map *SourceMap `json:"map"`
 }
 
 var (
-	jsImportRegex         = regexp.MustCompile(`\b(?:require|import)\s*\(?['"]([^'"]+)['"]\)?`)
-	jsImportFromRegex     = regexp.MustCompile(`\bfrom\s+['"]([^'"]+)['"]`)
-	jsRequireResolveRegex = regexp.MustCompile(`\brequire\.resolve\s*\(\s*['"]([^'"]+)['"]\s*\)`)
-	jsDynamicImportRegex  = regexp.MustCompile(`\bimport\s*\(\s*['"]([^'"]+)['"]\s*\)`)
-	amdDefineRegex        = regexp.MustCompile(`\bdefine\s*\(\s*\[([^\]]+)\]`)
-	amdRequireRegex       = regexp.MustCompile(`\brequire\s*\(\s*\[([^\]]+)\]`)
+	amdDefineRegex  = regexp.MustCompile(`\bdefine\s*\(\s*\[([^\]]+)\]`)
+	amdRequireRegex = regexp.MustCompile(`\brequire\s*\(\s*\[([^\]]+)\]`)
 
 	npmInstallRegex = regexp.MustCompile(`npm\s+install\s+([a-zA-Z0-9@/_-]+)`)
 	yarnAddRegex    = regexp.MustCompile(`yarn\s+add\s+([a-zA-Z0-9@/_-]+)`)
@@ -90,19 +141,19 @@ var (
 	useArrayRegex      = regexp.MustCompile(`use:\s*\[([^\]]+)\]`)
 	presetPluginRegex  = regexp.MustCompile(`(?:presets?|plugins?):\s*\[([^\]]+)\]`)
 
-	cdnURLRegex     = regexp.MustCompile(`(?:https?://)?(?:unpkg\.com|cdn\.jsdelivr\.net|cdnjs\.cloudflare\.com)/(?:(?:npm|ajax/libs)/)?(@?[a-zA-Z0-9/_.-]+)`)
-	scriptSrcRegex  = regexp.MustCompile(`<script[^>]+src=['"]([^'"]+)['"]`)
-	importMapRegex  = regexp.MustCompile(`"(@?[a-zA-Z0-9/_-]+)":\s*['"]https?://[^'"]+['"]`)
+	cdnURLRegex               = regexp.MustCompile(`(?:https?://)?(?:unpkg\.com|cdn\.jsdelivr\.net|cdnjs\.cloudflare\.com)/(?:(?:npm|ajax/libs)/)?(@?[a-zA-Z0-9/_.-]+)`)
+	scriptSrcRegex            = regexp.MustCompile(`<script[^>]+src=['"]([^'"]+)['"]`)
+	importMapRegex            = regexp.MustCompile(`"(@?[a-zA-Z0-9/_-]+)":\s*['"]https?://[^'"]+['"]`)
 	sourceMapNodeModulesRegex = regexp.MustCompile(`webpack://[^/]*/(\.?/)?node_modules/(@?[^/]+(?:/[^/@]+)?)`)
 	sourceMapPackageRegex     = regexp.MustCompile(`/(@?[a-zA-Z0-9/_.-]+(?:/[a-zA-Z0-9/_.-]+)?)/`)
 	sourceMapFileRegex        = regexp.MustCompile(`node_modules/(@?[a-zA-Z0-9/_.-]+)/`)
 
-	webpackChunkRegex = regexp.MustCompile(`/\*\*\* WEBPACK CHUNK: (@?[a-zA-Z0-9/_-]+) \*\*\*/`)
+	webpackChunkRegex  = regexp.MustCompile(`/\*\*\* WEBPACK CHUNK: (@?[a-zA-Z0-9/_-]+) \*\*\*/`)
 	bundleCommentRegex = regexp.MustCompile(`/\*[^*]*(@?[a-zA-Z0-9/_-]+(?:/[a-zA-Z0-9/_-]+)?)[^*]*\*/`)
 
-	umdGlobalRegex     = regexp.MustCompile(`(?:window|global)\[?['"](@?[a-zA-Z0-9/_-]+)['"]?\]?\s*=`)
-	umdFactoryRegex    = regexp.MustCompile(`factory\s*\(\s*(?:require\s*\(\s*['"]([^'"]+)['"]|(['"][^'"]+['"]))`)
-	globalAssignRegex  = regexp.MustCompile(`(?:window|global)\.(@?[A-Za-z][A-Za-z0-9_$]*)\s*=`)
+	umdGlobalRegex    = regexp.MustCompile(`(?:window|global)\[?['"](@?[a-zA-Z0-9/_-]+)['"]?\]?\s*=`)
+	umdFactoryRegex   = regexp.MustCompile(`factory\s*\(\s*(?:require\s*\(\s*['"]([^'"]+)['"]|(['"][^'"]+['"]))`)
+	globalAssignRegex = regexp.MustCompile(`(?:window|global)\.(@?[A-Za-z][A-Za-z0-9_$]*)\s*=`)
 
 	minifiedCallRegex    = regexp.MustCompile(`\b[a-z]\(['"](@?[a-zA-Z0-9/_-]+)['"]`)
 	minifiedRequireRegex = regexp.MustCompile(`\b(?:n|r|e)\((\d+)\)`)
@@ -115,19 +166,27 @@ var (
 	cicdExtensions      = []string{".yml", ".yaml", ".sh", ".bash"}
 	docExtensions       = []string{".md", ".rst", ".txt"}
 	sourceMapExtensions = []string{".map"}
-
 )
 
 type Options struct {
-	Concurrency int
-	Timeout     int
-	Delay       int
-	DelayJitter int
-	Verbose     bool
-	Silence     bool
-	UserAgent   string
-	Proxy       string
-	Resolvers   []string
+	Concurrency     int
+	Timeout         int
+	Delay           int
+	DelayJitter     int
+	Verbose         bool
+	Silence         bool
+	UserAgent       string
+	Proxy           string
+	Resolvers       []string
+	Registries      []Registry  // registries to check packages against
+	CacheFile       string      // file to persist registry lookups to (optional)
+	CacheTTL        int         // lifetime of cached registry lookups (in hours)
+	IgnoreDirs      []string    // directory names skipped by ScanDir
+	SkipNodeModules bool        // skip node_modules directories in ScanDir
+	MinConfidence   float64     // packages with a lower confidence (0-1) are dropped before registry lookups
+	NodeVersion     NodeVersion // Node.js release whose builtin modules are skipped, zero for every release
+	CrawlDepth      int         // levels of scripts loaded by HTML pages to fetch, 0 to only scan the given urls
+	CrawlHosts      []string    // hosts other than the page's own to fetch crawled scripts from (e.g. *.example.com)
 }
 
 // DefaultOptions returns default options
@@ -138,6 +197,9 @@ func DefaultOptions() *Options {
 		Delay:       0,
 		DelayJitter: 0,
 		UserAgent:   "npmjack",
+		Registries:  []Registry{{URL: "https://registry.npmjs.com", Role: RolePublic}},
+		CacheTTL:    24,
+		IgnoreDirs:  []string{".git"},
 	}
 }
 
@@ -161,16 +223,216 @@ func NewRunner() *Runner {
 		Timeout:   time.Duration(options.Timeout) * time.Second,
 	}
 
-	return &Runner{
+	r := &Runner{
 		Results:  make(chan Result),
-		Visited:  make(map[string]bool),
+		Visited:  NewMemoryVisited(),
 		Options:  *options,
 		client:   client,
 		resolver: resolver,
+		cache:    newRegistryCache(time.Duration(options.CacheTTL) * time.Hour),
 	}
+	r.extractors = r.builtinExtractors()
+
+	return r
 }
 
+// Run scans the given URLs and sends a result per URL to Results. It is
+// equivalent to RunContext with a background context
 func (r *Runner) Run(urls ...string) {
+	r.RunContext(context.Background(), urls...)
+}
+
+// RunContext scans the given URLs and sends a result per URL to Results, and
+// one per URL found in their responses once those have all been scanned. Each
+// request gets its own timeout, which starts when the request begins. When
+// ctx is cancelled no new requests are started, in-flight requests are
+// aborted and RunContext returns once they have finished. Results of scans
+// that finished are still sent if read promptly, those of aborted requests
+// are dropped
+func (r *Runner) RunContext(ctx context.Context, urls ...string) {
+	if !r.begin() {
+		return
+	}
+	defer r.end()
+
+	s := &scan{referrers: make(map[string][]string)}
+	for _, url := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+		r.enqueue(ctx, s, url, "", 0)
+	}
+	s.wg.Wait()
+
+	for _, res := range s.heldResults() {
+		r.sendResult(ctx, res)
+	}
+}
+
+// scan is the state of a RunContext call
+type scan struct {
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	held      []Result            // results of urls found in other responses
+	referrers map[string][]string // urls each url was found in
+}
+
+// addReferrer records that url was found in referrer
+func (s *scan) addReferrer(url, referrer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.Contains(s.referrers[url], referrer) {
+		s.referrers[url] = append(s.referrers[url], referrer)
+	}
+}
+
+// hold keeps the result of a url found in another response until the call
+// ends, as more responses may refer to it until then
+func (s *scan) hold(res Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held = append(s.held, res)
+}
+
+// heldResults returns the held results with every url they were found in
+func (s *scan) heldResults() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.held {
+		s.held[i].Referrers = slices.Sorted(slices.Values(s.referrers[s.held[i].RequestURL]))
+	}
+	return s.held
+}
+
+// enqueue scans the URL unless it has been visited before, once a slot of
+// the concurrency limit is free. URLs found while scanning it, such as the
+// source map of a script, are enqueued in turn with the URL as referrer, and
+// their results sent once the RunContext call ends so that every referrer
+// is recorded. depth is the number of crawl steps from a given URL: scripts
+// of an HTML page are only crawled while it is below Options.CrawlDepth, and
+// URLs found in crawled responses only followed within the crawl hosts
+func (r *Runner) enqueue(ctx context.Context, s *scan, url, referrer string, depth int) {
+	log.Debugf("Running on %s", url)
+
+	url, err := normalizeURLString(url)
+	url = trimURLParams(url)
+	if err != nil {
+		log.Warnf("%v", err.Error())
+		return
+	}
+
+	if r.hasFileExtension(url) && urlutil.IsMediaExt(urlutil.GetExt(url)) {
+		return
+	}
+	if referrer != "" {
+		s.addReferrer(url, referrer)
+	}
+	if !r.claim(url) {
+		return
+	}
+
+	select {
+	case r.sem <- struct{}{}:
+	case <-ctx.Done():
+		r.release(url, false)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		result, next := r.scrapePackages(ctx, url, r.client)
+		r.release(url, result.Error == nil && ctx.Err() == nil)
+		if referrer == "" {
+			r.sendResult(ctx, result)
+		} else {
+			s.hold(result)
+		}
+		time.Sleep(time.Millisecond * 10) // make room for processing results
+		<-r.sem
+
+		for _, l := range next {
+			if ctx.Err() != nil {
+				break
+			}
+			switch {
+			case !l.crawl && (depth == 0 || r.inCrawlScope(l.url, url)):
+				// crawled responses may not lead off the crawl hosts either
+				r.enqueue(ctx, s, l.url, url, depth)
+			case !l.crawl:
+				log.Debugf("Not following %s found in %s", l.url, url)
+			case depth < r.Options.CrawlDepth && r.inCrawlScope(l.url, url):
+				r.enqueue(ctx, s, l.url, url, depth+1)
+			default:
+				log.Debugf("Not crawling %s found in %s", l.url, url)
+			}
+		}
+	}()
+
+	sleepContext(ctx, r.getDelay()*time.Millisecond) // delay between requests
+}
+
+// claim reserves the url or file key for scanning. It reports false if the
+// key has been visited or is being scanned already
+func (r *Runner) claim(key string) bool {
+	r.claimMu.Lock()
+	defer r.claimMu.Unlock()
+
+	if r.claimed[key] || r.Visited.Has(key) {
+		return false
+	}
+	if r.claimed == nil {
+		r.claimed = make(map[string]bool)
+	}
+	r.claimed[key] = true
+	return true
+}
+
+// release ends the claim on key, marking it visited if its scan finished.
+// Keys whose scan failed or was cancelled are left unvisited, so a resumed
+// scan picks them up again
+func (r *Runner) release(key string, scanned bool) {
+	r.claimMu.Lock()
+	defer r.claimMu.Unlock()
+
+	if scanned {
+		r.Visited.Visit(key)
+	}
+	delete(r.claimed, key)
+}
+
+// begin marks the start of a scan, applying the options on the first one.
+// It reports false if the runner has been closed
+func (r *Runner) begin() bool {
+	r.mu.RLock()
+	if r.closed {
+		r.mu.RUnlock()
+		log.Warnf("Runner is closed, ignoring scan")
+		return false
+	}
+	r.setupOnce.Do(r.setup)
+	return true
+}
+
+// end marks the end of a scan started with begin
+func (r *Runner) end() {
+	r.mu.RUnlock()
+}
+
+// setup applies the options to the logger, cache, resolver and http client
+func (r *Runner) setup() {
+	SetLogLevel(&r.Options)
+	r.loadCache()
+	r.started = true
+
+	if r.Options.Concurrency < 1 {
+		r.Options.Concurrency = 1
+	}
+	r.sem = make(chan struct{}, r.Options.Concurrency)
+	r.client.Timeout = time.Duration(r.Options.Timeout) * time.Second
 
 	if len(r.Options.Resolvers) > 0 {
 		r.resolver = NewCustomResolver(r.Options.Resolvers, time.Duration(r.Options.Timeout)*time.Second)
@@ -192,55 +454,113 @@ func (r *Runner) Run(urls ...string) {
 			}
 		}
 	}
+}
 
-	sem := make(chan struct{}, r.Options.Concurrency)
-	var wg sync.WaitGroup
+// resultGracePeriod is how long a finished result is still offered on
+// Results once ctx is cancelled, so scans don't block on a consumer that
+// stopped reading
+const resultGracePeriod = time.Second
+
+// sendResult sends the result of a scan to Results unless ctx is cancelled
+// first. Results of scans that failed because ctx was cancelled are dropped,
+// finished ones are still sent if they are read within resultGracePeriod
+func (r *Runner) sendResult(ctx context.Context, res Result) {
+	if res.Error == nil || ctx.Err() == nil {
+		select {
+		case r.Results <- res:
+			return
+		case <-ctx.Done():
+		}
+	}
 
-	for _, url := range urls {
-		log.Debugf("Running on %s", url)
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Options.Timeout)*time.Second)
-		defer cancel()
-		url, err := normalizeURLString(url)
-		url = trimURLParams(url)
-		if err != nil {
-			log.Warnf("%v", err.Error())
-			continue
+	if res.Error == nil {
+		timer := time.NewTimer(resultGracePeriod)
+		defer timer.Stop()
+
+		select {
+		case r.Results <- res:
+			return
+		case <-timer.C:
 		}
+	}
+	log.Debugf("Dropping result for %s: %v", res.RequestURL, ctx.Err())
+}
 
-		if !r.Visited[url] {
-			r.Visited[url] = true
-			if r.hasFileExtension(url) && urlutil.IsMediaExt(urlutil.GetExt(url)) {
-				continue
-			}
+// sleepContext sleeps for the given duration or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
 
-			sem <- struct{}{}
-			wg.Add(1)
-			go func(u string) {
-				defer func() { <-sem }()
-				defer wg.Done()
-				r.Results <- r.scrapePackages(ctx, u, r.client)
-				time.Sleep(time.Millisecond * 10) // make room for processing results
-			}(url)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-			time.Sleep(r.getDelay() * time.Millisecond) // delay between requests
-		}
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
 	}
-	wg.Wait()
 }
 
-// Close closes the Results channel
+// Close waits for running scans to return, then closes the Results channel
+// and persists the registry cache and visited state. Results must be read
+// until it is closed. Calling Close more than once has no effect
 func (r *Runner) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.closed = true
 	close(r.Results)
+
+	if r.started {
+		r.saveCache()
+	}
+	if closer, ok := r.Visited.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Warnf("Could not save visited state: %v", err)
+		}
+	}
+}
+
+// loadCache applies the cache options and loads the cache file, if any
+func (r *Runner) loadCache() {
+	r.cache.ttl = time.Duration(r.Options.CacheTTL) * time.Hour
+	if r.Options.CacheFile == "" {
+		return
+	}
+	if err := r.cache.load(r.Options.CacheFile); err != nil {
+		log.Warnf("Could not load registry cache: %v", err)
+	}
 }
 
-func (r *Runner) scrapePackages(ctx context.Context, url string, client *http.Client) Result {
+// saveCache writes the registry cache to the cache file, if any
+func (r *Runner) saveCache() {
+	if r.Options.CacheFile == "" {
+		return
+	}
+	if err := r.cache.save(r.Options.CacheFile); err != nil {
+		log.Warnf("Could not save registry cache: %v", err)
+	}
+}
+
+// scrapePackages fetches the URL and checks the packages found in the
+// response, and returns the URLs found in the response that should be
+// scanned next: the source map of a script or stylesheet and, when
+// crawling, the scripts of an HTML page and the chunks of a webpack
+// runtime or build manifest. The request and body read are bound by the
+// request timeout, registry lookups only by ctx
+func (r *Runner) scrapePackages(ctx context.Context, url string, client *http.Client) (Result, []link) {
 	log.Debugf("Scraping packages from %s", url)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(r.Options.Timeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
 	if err != nil {
 		log.Warnf("%v", err.Error())
-		return Result{RequestURL: url, Error: err}
+		return Result{RequestURL: url, Error: err}, nil
 	}
 
 	if r.Options.UserAgent != "" {
@@ -250,7 +570,7 @@ func (r *Runner) scrapePackages(ctx context.Context, url string, client *http.Cl
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Warnf("%v", err.Error())
-		return Result{RequestURL: url, Error: err}
+		return Result{RequestURL: url, Error: err}, nil
 	}
 
 	defer resp.Body.Close()
@@ -261,58 +581,116 @@ func (r *Runner) scrapePackages(ctx context.Context, url string, client *http.Cl
 		Error:      err,
 	}
 
-	seenPackages := make(map[string]bool)
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Warnf("Error reading response body: %v", err)
-		return Result{RequestURL: url, Error: err}
+		return Result{RequestURL: url, Error: err}, nil
 	}
 
-	packages := r.extractPackages(url, string(body))
+	cancel()
+	contentType := mediaType(resp.Header.Get("Content-Type"))
+	res.Packages = r.checkPackages(ctx, url, contentType, body)
 
-	for _, pkg := range packages {
-		if !seenPackages[pkg.Name] {
-			if r.isPackageClaimed(pkg.Name) {
-				pkg.Claimed = true
+	var next []link
+	if r.isScriptOrStylesheet(url, contentType) {
+		if mapURL, ok := sourceMapURL(resp.Header, body, resp.Request.URL); ok {
+			next = append(next, link{url: mapURL})
+		}
+		if r.Options.CrawlDepth > 0 {
+			for _, chunkURL := range webpackChunkURLs(string(body), resp.Request.URL) {
+				next = append(next, link{url: chunkURL, crawl: true})
 			}
-			res.Packages = append(res.Packages, pkg)
-			seenPackages[pkg.Name] = true
 		}
 	}
+	if r.Options.CrawlDepth > 0 && r.isBuildManifestFile(url) {
+		next = append(next, buildManifestLinks(string(body), resp.Request.URL)...)
+	}
+	if r.Options.CrawlDepth > 0 && r.isHTMLPage(url, contentType) {
+		next = append(next, htmlAssetLinks(body, resp.Request.URL)...)
+	}
 
-	return res
+	return res, next
 }
 
-func (r *Runner) extractPackages(url, content string) []Package {
-	var packages []Package
+// checkPackages extracts packages from the body and checks each unique
+// package with sufficient confidence against the registries. source is the
+// URL or file path the body was read from
+func (r *Runner) checkPackages(ctx context.Context, source, contentType string, body []byte) []Package {
+	var unique []Package
+	seenPackages := make(map[string]int) // index of each package in unique
+
+	for _, pkg := range r.extractPackages(source, contentType, body) {
+		if i, ok := seenPackages[pkg.FullName()]; ok {
+			unique[i].Evidence = mergeEvidence(unique[i].Evidence, pkg.Evidence)
+			if unique[i].ScopeRegistry == "" {
+				unique[i].ScopeRegistry = pkg.ScopeRegistry
+			}
+			continue
+		}
 
-	if r.isJSONFile(url) {
-		packages = append(packages, r.extractFromJSON(content)...)
+		pkg.Evidence = mergeEvidence(nil, pkg.Evidence)
+		seenPackages[pkg.FullName()] = len(unique)
+		unique = append(unique, pkg)
 	}
 
-	if r.isConfigFile(url) {
-		packages = append(packages, r.extractFromConfigFile(content)...)
-	}
+	var packages []Package
+	for _, pkg := range unique {
+		if ctx.Err() != nil {
+			break
+		}
 
-	if r.isCICDFile(url) {
-		packages = append(packages, r.extractFromCICD(content)...)
-	}
+		scorePackage(&pkg)
+		if pkg.Confidence < r.Options.MinConfidence {
+			log.Debugf("Skipping %s with confidence %.2f", pkg.FullName(), pkg.Confidence)
+			continue
+		}
 
-	if r.isDocFile(url) {
-		packages = append(packages, r.extractFromDocumentation(content)...)
+		r.claimPackage(ctx, &pkg)
+		packages = append(packages, pkg)
 	}
 
-	if r.isSourceMapFile(url) {
-		packages = append(packages, r.extractFromSourceMap(content)...)
+	return packages
+}
+
+// extractPackages runs the registered extractors on the body and returns the
+// packages with valid names, with their evidence located in the body
+func (r *Runner) extractPackages(filename, contentType string, body []byte) []Package {
+	var packages []Package
+
+	for _, extractor := range r.Extractors() {
+		found := runExtractor(extractor, filename, contentType, body)
+		for i := range found {
+			if len(found[i].Evidence) == 0 {
+				found[i].Evidence = []Evidence{{Method: extractor.Name(), Offset: -1}}
+			}
+			for j := range found[i].Evidence {
+				if found[i].Evidence[j].Extractor == "" {
+					found[i].Evidence[j].Extractor = extractor.Name()
+				}
+			}
+		}
+		packages = append(packages, found...)
 	}
+	locateEvidence(packages, filename, string(body))
+	scoreEvidence(packages)
 
-	packages = append(packages, r.extractFromJavaScript(content)...)
+	var valid []Package
+	for _, pkg := range packages {
+		if err := pkg.validate(); err != nil {
+			log.Debugf("Skipping %v", err)
+			continue
+		}
+		valid = append(valid, pkg)
+	}
 
-	return packages
+	return valid
 }
 
 func (r *Runner) isJSONFile(url string) bool {
+	// build manifests are read by their own extractor, not as package.json
+	if r.isBuildManifestFile(url) {
+		return false
+	}
 	for _, ext := range jsonExtensions {
 		if strings.HasSuffix(url, ext) {
 			return true
@@ -388,11 +766,11 @@ func (r *Runner) extractFromJSON(content string) []Package {
 	var packages []Package
 
 	if pkgJSON := r.parsePackageJSON(content); pkgJSON != nil {
-		packages = append(packages, r.extractFromPackageJSON(pkgJSON)...)
+		packages = append(packages, r.extractFromPackageJSON(pkgJSON, content)...)
 	}
 
 	if lockJSON := r.parsePackageLockJSON(content); lockJSON != nil {
-		packages = append(packages, r.extractFromPackageLockJSON(lockJSON)...)
+		packages = append(packages, r.extractFromPackageLockJSON(lockJSON, content)...)
 	}
 
 	// yarn.lock
@@ -417,7 +795,7 @@ func (r *Runner) parsePackageLockJSON(content string) *PackageLockJSON {
 	return &lockFile
 }
 
-func (r *Runner) extractFromPackageJSON(pkg *PackageJSON) []Package {
+func (r *Runner) extractFromPackageJSON(pkg *PackageJSON, content string) []Package {
 	var packages []Package
 
 	dependencies := []map[string]string{
@@ -428,19 +806,28 @@ func (r *Runner) extractFromPackageJSON(pkg *PackageJSON) []Package {
 	}
 
 	for _, deps := range dependencies {
-		for name := range deps {
-			packages = append(packages, r.createPackageFromName(name))
+		for name, version := range deps {
+			packages = append(packages, r.createPackageFromName(name, "package-json", indexQuoted(content, name)))
+			packages = append(packages, r.npmAliasPackages(version, "package-json", content)...)
 		}
 	}
 
-	for _, name := range pkg.BundledDependencies {
-		packages = append(packages, r.createPackageFromName(name))
+	for name := range pkg.PeerDependenciesMeta {
+		packages = append(packages, r.createPackageFromName(name, "package-json", indexQuoted(content, name)))
+	}
+
+	for _, name := range append(pkg.BundledDependencies, jsonStrings(pkg.BundleDependencies)...) {
+		packages = append(packages, r.createPackageFromName(name, "package-json", indexQuoted(content, name)))
 	}
 
+	packages = append(packages, r.packageJSONNamePackages(pkg, content)...)
+	packages = append(packages, r.packageJSONOverridePackages(pkg, content)...)
+	packages = append(packages, r.packageJSONWorkspacePackages(pkg, content)...)
+
 	return packages
 }
 
-func (r *Runner) extractFromPackageLockJSON(lockFile *PackageLockJSON) []Package {
+func (r *Runner) extractFromPackageLockJSON(lockFile *PackageLockJSON, content string) []Package {
 	var packages []Package
 
 	for packagePath, entry := range lockFile.Packages {
@@ -448,12 +835,16 @@ func (r *Runner) extractFromPackageLockJSON(lockFile *PackageLockJSON) []Package
 			parts := strings.Split(packagePath, "node_modules/")
 			if len(parts) > 1 {
 				name := parts[len(parts)-1]
-				packages = append(packages, r.createPackageFromName(name))
+				offset := indexQuoted(content, packagePath)
+				if offset != -1 {
+					offset += len(packagePath) - len(name)
+				}
+				packages = append(packages, r.createPackageFromName(name, "package-lock", offset))
 			}
 		}
 
 		for depName := range entry.Dependencies {
-			packages = append(packages, r.createPackageFromName(depName))
+			packages = append(packages, r.createPackageFromName(depName, "package-lock", indexQuoted(content, depName)))
 		}
 	}
 
@@ -461,32 +852,41 @@ func (r *Runner) extractFromPackageLockJSON(lockFile *PackageLockJSON) []Package
 }
 
 func (r *Runner) extractFromYarnLock(content string) []Package {
+	if yarnBerryMetadataRegex.MatchString(content) {
+		return r.extractFromYarnBerryLock(content)
+	}
+
 	var packages []Package
 
 	scopedPackageRegex := regexp.MustCompile(`^"(@[^/]+/[^@"]+)(?:@[^"]*)?":`)
 	normalPackageRegex := regexp.MustCompile(`^"([^@"]+)(?:@[^"]*)?":`)
 	dependencyRegex := regexp.MustCompile(`^\s{4}"?(@?[a-zA-Z0-9/@_-]+)"?\s+"([^"]+)"`)
 
+	offset := 0
 	lines := strings.Split(content, "\n")
 	for _, line := range lines {
+		lineStart := offset
+		offset += len(line) + 1
+
 		originalLine := line
 		line = strings.TrimSpace(line)
 
 		if strings.HasPrefix(line, "#") || line == "" {
 			continue
 		}
+		lineStart += strings.Index(originalLine, line)
 
 		// scoped packages
-		if matches := scopedPackageRegex.FindStringSubmatch(line); matches != nil {
-			name := matches[1]
-			packages = append(packages, r.createPackageFromName(name))
+		if matches := scopedPackageRegex.FindStringSubmatchIndex(line); matches != nil {
+			name := line[matches[2]:matches[3]]
+			packages = append(packages, r.createPackageFromName(name, "yarn-lock", lineStart+matches[2]))
 			continue
 		}
 
 		// normal packages
-		if matches := normalPackageRegex.FindStringSubmatch(line); matches != nil {
-			name := matches[1]
-			packages = append(packages, r.createPackageFromName(name))
+		if matches := normalPackageRegex.FindStringSubmatchIndex(line); matches != nil {
+			name := line[matches[2]:matches[3]]
+			packages = append(packages, r.createPackageFromName(name, "yarn-lock", lineStart+matches[2]))
 			continue
 		}
 
@@ -497,7 +897,7 @@ func (r *Runner) extractFromYarnLock(content string) []Package {
 			if matches := dependencyRegex.FindStringSubmatch(originalLine); matches != nil {
 				name := matches[1]
 				if !r.isBuiltinModule(name) && r.looksLikePackageName(name) {
-					packages = append(packages, r.createPackageFromName(name))
+					packages = append(packages, r.createPackageFromName(name, "yarn-lock-dependency", indexFrom(line, name, lineStart)))
 				}
 			}
 		}
@@ -507,51 +907,65 @@ func (r *Runner) extractFromYarnLock(content string) []Package {
 }
 
 func (r *Runner) extractFromSourceMap(content string) []Package {
-	var packages []Package
-
 	var sourceMap SourceMap
 	if err := json.Unmarshal([]byte(content), &sourceMap); err != nil {
-		return packages
+		return nil
+	}
+	return r.extractFromParsedSourceMap(&sourceMap, content)
+}
+
+// extractFromParsedSourceMap finds the packages of a source map parsed from
+// content, descending into the sections of index maps, whose maps are
+// nested in the same content
+func (r *Runner) extractFromParsedSourceMap(sourceMap *SourceMap, content string) []Package {
+	var packages []Package
+
+	for _, section := range sourceMap.Sections {
+		if section.Map != nil {
+			packages = append(packages, r.extractFromParsedSourceMap(section.Map, content)...)
+		}
 	}
 
 	for _, source := range sourceMap.Sources {
-		if matches := sourceMapNodeModulesRegex.FindAllStringSubmatch(source, -1); matches != nil {
+		base := indexQuoted(content, source)
+
+		if matches := sourceMapNodeModulesRegex.FindAllStringSubmatchIndex(source, -1); matches != nil {
 			for _, match := range matches {
-				if len(match) > 2 {
-					pkgName := match[2]
-					if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-						packages = append(packages, r.createPackageFromName(pkgName))
-					}
+				if pkgName, ok := r.specifierPackageName(source[match[4]:match[5]]); ok && r.looksLikePackageName(pkgName) {
+					packages = append(packages, r.createPackageFromName(pkgName, "sourcemap-node-modules", offsetAt(base, match[4])))
 				}
 			}
 		}
 
-		if matches := sourceMapPackageRegex.FindAllStringSubmatch(source, -1); matches != nil {
+		if matches := sourceMapPackageRegex.FindAllStringSubmatchIndex(source, -1); matches != nil {
 			for _, match := range matches {
-				if len(match) > 1 {
-					pkgName := match[1]
-					if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-						packages = append(packages, r.createPackageFromName(pkgName))
-					}
+				pkgName := source[match[2]:match[3]]
+				if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
+					packages = append(packages, r.createPackageFromName(pkgName, "sourcemap-path", offsetAt(base, match[2])))
 				}
 			}
 		}
 
-		if matches := sourceMapFileRegex.FindAllStringSubmatch(source, -1); matches != nil {
+		if matches := sourceMapFileRegex.FindAllStringSubmatchIndex(source, -1); matches != nil {
 			for _, match := range matches {
-				if len(match) > 1 {
-					pkgName := match[1]
-					if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-						packages = append(packages, r.createPackageFromName(pkgName))
-					}
+				if pkgName, ok := r.specifierPackageName(source[match[2]:match[3]]); ok && r.looksLikePackageName(pkgName) {
+					packages = append(packages, r.createPackageFromName(pkgName, "sourcemap-file", offsetAt(base, match[2])))
 				}
 			}
 		}
 	}
 
-	for _, sourceContent := range sourceMap.SourcesContent {
+	// packages imported by the original sources are located in those sources
+	for i, sourceContent := range sourceMap.SourcesContent {
 		if sourceContent != "" {
-			packages = append(packages, r.extractFromJavaScript(sourceContent)...)
+			source := "sourcesContent[" + strconv.Itoa(i) + "]"
+			if i < len(sourceMap.Sources) {
+				source = sourceMap.Sources[i]
+			}
+
+			found := r.extractFromJavaScript(sourceContent)
+			locateEvidence(found, source, sourceContent)
+			packages = append(packages, found...)
 		}
 	}
 
@@ -561,66 +975,59 @@ func (r *Runner) extractFromSourceMap(content string) []Package {
 func (r *Runner) extractFromJavaScript(content string) []Package {
 	var packages []Package
 
-	patterns := []*regexp.Regexp{
-		jsImportRegex,
-		jsImportFromRegex,
-		jsRequireResolveRegex,
-		jsDynamicImportRegex,
-	}
-
-	for _, pattern := range patterns {
-		matches := pattern.FindAllStringSubmatch(content, -1)
-		for _, match := range matches {
-			if len(match) > 1 && !r.isBuiltinModule(match[1]) {
-				packages = append(packages, r.createPackageFromName(match[1]))
-			}
+	for _, spec := range lexModuleSpecifiers(content) {
+		if name, ok := r.specifierPackageName(spec.specifier); ok {
+			packages = append(packages, r.createPackageFromName(name, spec.method, spec.offset))
 		}
 	}
 
-	amdDefineMatches := amdDefineRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range amdDefineMatches {
-		if len(match) > 1 {
-			deps := r.parseAMDDependencies(match[1])
-			for _, dep := range deps {
-				packages = append(packages, r.createPackageFromName(dep))
-			}
-		}
+	amdPatterns := []struct {
+		method  string
+		pattern *regexp.Regexp
+	}{
+		{"amd-define", amdDefineRegex},
+		{"amd-require", amdRequireRegex},
 	}
 
-	amdRequireMatches := amdRequireRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range amdRequireMatches {
-		if len(match) > 1 {
-			deps := r.parseAMDDependencies(match[1])
+	for _, p := range amdPatterns {
+		matches := p.pattern.FindAllStringSubmatchIndex(content, -1)
+		for _, match := range matches {
+			depString := content[match[2]:match[3]]
+			deps := r.parseAMDDependencies(depString)
 			for _, dep := range deps {
-				packages = append(packages, r.createPackageFromName(dep))
+				if name, ok := r.specifierPackageName(dep); ok {
+					packages = append(packages, r.createPackageFromName(name, p.method, indexFrom(depString, dep, match[2])))
+				}
 			}
 		}
 	}
 
 	requireConfigRegex := regexp.MustCompile(`paths:\s*\{([^}]+)\}`)
-	configMatches := requireConfigRegex.FindAllStringSubmatch(content, -1)
+	configMatches := requireConfigRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range configMatches {
-		if len(match) > 1 {
-			pathRegex := regexp.MustCompile(`['"]([^'"]+)['"]\s*:\s*['"][^'"]+['"]`)
-			pathMatches := pathRegex.FindAllStringSubmatch(match[1], -1)
-			for _, pm := range pathMatches {
-				if len(pm) > 1 && !r.isBuiltinModule(pm[1]) {
-					packages = append(packages, r.createPackageFromName(pm[1]))
-				}
+		pathsBlock := content[match[2]:match[3]]
+		pathRegex := regexp.MustCompile(`['"]([^'"]+)['"]\s*:\s*['"][^'"]+['"]`)
+		pathMatches := pathRegex.FindAllStringSubmatchIndex(pathsBlock, -1)
+		for _, pm := range pathMatches {
+			if name, ok := r.specifierPackageName(pathsBlock[pm[2]:pm[3]]); ok {
+				packages = append(packages, r.createPackageFromName(name, "requirejs-paths", match[2]+pm[2]))
 			}
 		}
 	}
 
-	docPatterns := []*regexp.Regexp{
-		npmInstallRegex,
-		yarnAddRegex,
+	docPatterns := []struct {
+		method  string
+		pattern *regexp.Regexp
+	}{
+		{"npm-install", npmInstallRegex},
+		{"yarn-add", yarnAddRegex},
 	}
 
-	for _, pattern := range docPatterns {
-		matches := pattern.FindAllStringSubmatch(content, -1)
+	for _, p := range docPatterns {
+		matches := p.pattern.FindAllStringSubmatchIndex(content, -1)
 		for _, match := range matches {
-			if len(match) > 1 && !r.isBuiltinModule(match[1]) {
-				packages = append(packages, r.createPackageFromName(match[1]))
+			if name, ok := r.specifierPackageName(content[match[2]:match[3]]); ok {
+				packages = append(packages, r.createPackageFromName(name, p.method, match[2]))
 			}
 		}
 	}
@@ -630,6 +1037,7 @@ func (r *Runner) extractFromJavaScript(content string) []Package {
 	packages = append(packages, r.extractFromUMDPatterns(content)...)
 	packages = append(packages, r.extractFromMinifiedCode(content)...)
 	packages = append(packages, r.extractFromWebpackExternals(content)...)
+	packages = append(packages, r.extractFromInlineSourceMaps(content)...)
 
 	return packages
 }
@@ -637,30 +1045,24 @@ func (r *Runner) extractFromJavaScript(content string) []Package {
 func (r *Runner) extractFromCDNUrls(content string) []Package {
 	var packages []Package
 
-	scriptMatches := scriptSrcRegex.FindAllStringSubmatch(content, -1)
+	scriptMatches := scriptSrcRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range scriptMatches {
-		if len(match) > 1 {
-			packages = append(packages, r.extractPackagesFromURL(match[1])...)
-		}
+		found := r.extractPackagesFromURL(content[match[2]:match[3]])
+		shiftEvidence(found, match[2])
+		packages = append(packages, found...)
 	}
 
-	cdnMatches := cdnURLRegex.FindAllStringSubmatch(content, -1)
+	cdnMatches := cdnURLRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range cdnMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
+		if pkgName, ok := r.specifierPackageName(content[match[2]:match[3]]); ok && r.looksLikePackageName(pkgName) {
+			packages = append(packages, r.createPackageFromName(pkgName, "cdn-url", match[2]))
 		}
 	}
 
-	importMapMatches := importMapRegex.FindAllStringSubmatch(content, -1)
+	importMapMatches := importMapRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range importMapMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
+		if pkgName, ok := r.specifierPackageName(content[match[2]:match[3]]); ok && r.looksLikePackageName(pkgName) {
+			packages = append(packages, r.createPackageFromName(pkgName, "import-map", match[2]))
 		}
 	}
 
@@ -670,13 +1072,10 @@ func (r *Runner) extractFromCDNUrls(content string) []Package {
 func (r *Runner) extractPackagesFromURL(url string) []Package {
 	var packages []Package
 
-	matches := cdnURLRegex.FindAllStringSubmatch(url, -1)
+	matches := cdnURLRegex.FindAllStringSubmatchIndex(url, -1)
 	for _, match := range matches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
+		if pkgName, ok := r.specifierPackageName(url[match[2]:match[3]]); ok && r.looksLikePackageName(pkgName) {
+			packages = append(packages, r.createPackageFromName(pkgName, "script-src", match[2]))
 		}
 	}
 
@@ -686,23 +1085,19 @@ func (r *Runner) extractPackagesFromURL(url string) []Package {
 func (r *Runner) extractFromBundleComments(content string) []Package {
 	var packages []Package
 
-	chunkMatches := webpackChunkRegex.FindAllStringSubmatch(content, -1)
+	chunkMatches := webpackChunkRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range chunkMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
+		pkgName := content[match[2]:match[3]]
+		if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
+			packages = append(packages, r.createPackageFromName(pkgName, "webpack-chunk", match[2]))
 		}
 	}
 
-	commentMatches := bundleCommentRegex.FindAllStringSubmatch(content, -1)
+	commentMatches := bundleCommentRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range commentMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
+		pkgName := content[match[2]:match[3]]
+		if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
+			packages = append(packages, r.createPackageFromName(pkgName, "bundle-comment", match[2]))
 		}
 	}
 
@@ -728,35 +1123,32 @@ func (r *Runner) parseAMDDependencies(depString string) []string {
 func (r *Runner) extractFromUMDPatterns(content string) []Package {
 	var packages []Package
 
-	globalMatches := umdGlobalRegex.FindAllStringSubmatch(content, -1)
+	globalMatches := umdGlobalRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range globalMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
+		pkgName := content[match[2]:match[3]]
+		if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
+			packages = append(packages, r.createPackageFromName(pkgName, "umd-global", match[2]))
 		}
 	}
 
-	factoryMatches := umdFactoryRegex.FindAllStringSubmatch(content, -1)
+	factoryMatches := umdFactoryRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range factoryMatches {
-		for i := 1; i < len(match); i++ {
-			if match[i] != "" {
-				pkgName := strings.Trim(match[i], `'"`)
-				if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-					packages = append(packages, r.createPackageFromName(pkgName))
+		for i := 2; i+1 < len(match); i += 2 {
+			if match[i] != -1 && match[i] != match[i+1] {
+				group := content[match[i]:match[i+1]]
+				spec := strings.Trim(group, `'"`)
+				if pkgName, ok := r.specifierPackageName(spec); ok && r.looksLikePackageName(pkgName) {
+					packages = append(packages, r.createPackageFromName(pkgName, "umd-factory", indexFrom(group, spec, match[i])))
 				}
 			}
 		}
 	}
 
-	assignMatches := globalAssignRegex.FindAllStringSubmatch(content, -1)
+	assignMatches := globalAssignRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range assignMatches {
-		if len(match) > 1 {
-			pkgName := strings.ToLower(match[1])
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
+		pkgName := strings.ToLower(content[match[2]:match[3]])
+		if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
+			packages = append(packages, r.createPackageFromName(pkgName, "global-assign", match[2]))
 		}
 	}
 
@@ -766,32 +1158,25 @@ func (r *Runner) extractFromUMDPatterns(content string) []Package {
 func (r *Runner) extractFromMinifiedCode(content string) []Package {
 	var packages []Package
 
-	callMatches := minifiedCallRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range callMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
-			}
-		}
+	patterns := []struct {
+		method    string
+		pattern   *regexp.Regexp
+		specifier bool // whether the match is a module specifier to normalize
+	}{
+		{"minified-call", minifiedCallRegex, false},
+		{"parcel-require", parcelRequireRegex, true},
+		{"rollup-require", rollupBundleRegex, true},
 	}
 
-	parcelMatches := parcelRequireRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range parcelMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
+	for _, p := range patterns {
+		matches := p.pattern.FindAllStringSubmatchIndex(content, -1)
+		for _, match := range matches {
+			pkgName, ok := content[match[2]:match[3]], true
+			if p.specifier {
+				pkgName, ok = r.specifierPackageName(pkgName)
 			}
-		}
-	}
-
-	rollupMatches := rollupBundleRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range rollupMatches {
-		if len(match) > 1 {
-			pkgName := match[1]
-			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-				packages = append(packages, r.createPackageFromName(pkgName))
+			if ok && !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
+				packages = append(packages, r.createPackageFromName(pkgName, p.method, match[2]))
 			}
 		}
 	}
@@ -802,19 +1187,15 @@ func (r *Runner) extractFromMinifiedCode(content string) []Package {
 func (r *Runner) extractFromWebpackExternals(content string) []Package {
 	var packages []Package
 
-	externalMatches := webpackExternalRegex.FindAllStringSubmatch(content, -1)
+	externalMatches := webpackExternalRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, match := range externalMatches {
-		if len(match) > 1 {
-			externalsBlock := match[1]
-			keyRegex := regexp.MustCompile(`['"](@?[a-zA-Z0-9/_-]+)['"]`)
-			keyMatches := keyRegex.FindAllStringSubmatch(externalsBlock, -1)
-			for _, km := range keyMatches {
-				if len(km) > 1 {
-					pkgName := km[1]
-					if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
-						packages = append(packages, r.createPackageFromName(pkgName))
-					}
-				}
+		externalsBlock := content[match[2]:match[3]]
+		keyRegex := regexp.MustCompile(`['"](@?[a-zA-Z0-9/_-]+)['"]`)
+		keyMatches := keyRegex.FindAllStringSubmatchIndex(externalsBlock, -1)
+		for _, km := range keyMatches {
+			pkgName := externalsBlock[km[2]:km[3]]
+			if !r.isBuiltinModule(pkgName) && r.looksLikePackageName(pkgName) {
+				packages = append(packages, r.createPackageFromName(pkgName, "webpack-external", match[2]+km[2]))
 			}
 		}
 	}
@@ -831,20 +1212,26 @@ func (r *Runner) extractFromConfigFile(content string) []Package {
 
 	packages = append(packages, r.extractFromJavaScript(content)...)
 
-	configPatterns := []*regexp.Regexp{
-		loaderRegex,
-		stringLiteralRegex,
+	configPatterns := []struct {
+		method    string
+		pattern   *regexp.Regexp
+		specifier bool // whether the match is a module specifier to normalize
+	}{
+		{"config-loader", loaderRegex, true},
+		{"config-string", stringLiteralRegex, false},
 	}
 
-	for _, pattern := range configPatterns {
-		matches := pattern.FindAllStringSubmatch(content, -1)
+	for _, p := range configPatterns {
+		matches := p.pattern.FindAllStringSubmatchIndex(content, -1)
 		for _, match := range matches {
-			if len(match) > 1 {
-				name := match[1]
-				name = strings.Trim(name, `'"`)
-				if r.looksLikePackageName(name) && !r.isBuiltinModule(name) {
-					packages = append(packages, r.createPackageFromName(name))
-				}
+			group := content[match[2]:match[3]]
+			spec := strings.Trim(group, `'"`)
+			name, ok := spec, true
+			if p.specifier {
+				name, ok = r.specifierPackageName(spec)
+			}
+			if ok && r.looksLikePackageName(name) && !r.isBuiltinModule(name) {
+				packages = append(packages, r.createPackageFromName(name, p.method, indexFrom(group, spec, match[2])))
 			}
 		}
 	}
@@ -855,18 +1242,14 @@ func (r *Runner) extractFromConfigFile(content string) []Package {
 	}
 
 	for _, pattern := range arrayPatterns {
-		matches := pattern.FindAllStringSubmatch(content, -1)
+		matches := pattern.FindAllStringSubmatchIndex(content, -1)
 		for _, match := range matches {
-			if len(match) > 1 {
-				arrayContent := match[1]
-				stringMatches := regexp.MustCompile(`['"]([^'"]+)['"]`).FindAllStringSubmatch(arrayContent, -1)
-				for _, sm := range stringMatches {
-					if len(sm) > 1 {
-						name := sm[1]
-						if r.looksLikePackageName(name) && !r.isBuiltinModule(name) {
-							packages = append(packages, r.createPackageFromName(name))
-						}
-					}
+			arrayContent := content[match[2]:match[3]]
+			stringMatches := regexp.MustCompile(`['"]([^'"]+)['"]`).FindAllStringSubmatchIndex(arrayContent, -1)
+			for _, sm := range stringMatches {
+				name, ok := r.specifierPackageName(arrayContent[sm[2]:sm[3]])
+				if ok && r.looksLikePackageName(name) {
+					packages = append(packages, r.createPackageFromName(name, "config-array", match[2]+sm[2]))
 				}
 			}
 		}
@@ -878,9 +1261,13 @@ func (r *Runner) extractFromConfigFile(content string) []Package {
 func (r *Runner) extractFromCICD(content string) []Package {
 	var packages []Package
 
+	offset := 0
 	lines := strings.Split(content, "\n")
 
 	for _, line := range lines {
+		lineStart := offset
+		offset += len(line) + 1
+
 		if strings.HasPrefix(strings.TrimSpace(line), "#") || strings.TrimSpace(line) == "" {
 			continue
 		}
@@ -889,15 +1276,11 @@ func (r *Runner) extractFromCICD(content string) []Package {
 			cleaned := regexp.MustCompile(`npm\s+i(?:nstall)?\s*(?:-[gDS]\s+|--[a-z-]+\s+)*`).ReplaceAllString(line, "")
 			parts := strings.Fields(cleaned)
 			for _, part := range parts {
-				pkgName := strings.Split(part, "@")[0]
-				if pkgName != "" && !strings.HasPrefix(pkgName, "-") && r.looksLikePackageName(pkgName) {
-					packages = append(packages, r.createPackageFromName(pkgName))
+				if strings.HasPrefix(part, "-") {
+					continue
 				}
-				if strings.HasPrefix(part, "@") && strings.Contains(part, "/") {
-					scopedPkg := regexp.MustCompile(`(@[^/@]+/[^@]+)`).FindStringSubmatch(part)
-					if len(scopedPkg) > 1 {
-						packages = append(packages, r.createPackageFromName(scopedPkg[1]))
-					}
+				if pkgName, ok := NormalizeSpecifier(part); ok && r.looksLikePackageName(pkgName) {
+					packages = append(packages, r.createPackageFromName(pkgName, "cicd-npm-install", indexFrom(line, part, lineStart)))
 				}
 			}
 		}
@@ -906,15 +1289,11 @@ func (r *Runner) extractFromCICD(content string) []Package {
 			cleaned := regexp.MustCompile(`yarn\s+(?:global\s+)?add\s*(?:--[a-z-]+\s+)*`).ReplaceAllString(line, "")
 			parts := strings.Fields(cleaned)
 			for _, part := range parts {
-				pkgName := strings.Split(part, "@")[0]
-				if pkgName != "" && !strings.HasPrefix(pkgName, "-") && r.looksLikePackageName(pkgName) {
-					packages = append(packages, r.createPackageFromName(pkgName))
+				if strings.HasPrefix(part, "-") {
+					continue
 				}
-				if strings.HasPrefix(part, "@") && strings.Contains(part, "/") {
-					scopedPkg := regexp.MustCompile(`(@[^/@]+/[^@]+)`).FindStringSubmatch(part)
-					if len(scopedPkg) > 1 {
-						packages = append(packages, r.createPackageFromName(scopedPkg[1]))
-					}
+				if pkgName, ok := NormalizeSpecifier(part); ok && r.looksLikePackageName(pkgName) {
+					packages = append(packages, r.createPackageFromName(pkgName, "cicd-yarn-add", indexFrom(line, part, lineStart)))
 				}
 			}
 		}
@@ -923,25 +1302,28 @@ func (r *Runner) extractFromCICD(content string) []Package {
 			cleaned := regexp.MustCompile(`pnpm\s+(?:install|add)\s*`).ReplaceAllString(line, "")
 			parts := strings.Fields(cleaned)
 			for _, part := range parts {
-				if !strings.HasPrefix(part, "-") && r.looksLikePackageName(part) {
-					packages = append(packages, r.createPackageFromName(part))
+				if strings.HasPrefix(part, "-") {
+					continue
+				}
+				if pkgName, ok := NormalizeSpecifier(part); ok && r.looksLikePackageName(pkgName) {
+					packages = append(packages, r.createPackageFromName(pkgName, "cicd-pnpm-add", indexFrom(line, part, lineStart)))
 				}
 			}
 		}
 
 		if strings.Contains(line, "npx ") {
 			npxRegex := regexp.MustCompile(`npx\s+(@?[a-zA-Z0-9/_-]+)`)
-			matches := npxRegex.FindAllStringSubmatch(line, -1)
+			matches := npxRegex.FindAllStringSubmatchIndex(line, -1)
 			for _, match := range matches {
-				if len(match) > 1 {
-					packages = append(packages, r.createPackageFromName(match[1]))
-				}
+				packages = append(packages, r.createPackageFromName(line[match[2]:match[3]], "cicd-npx", lineStart+match[2]))
 			}
 		}
 
 		if strings.HasPrefix(strings.TrimSpace(line), "RUN ") {
 			runContent := strings.TrimPrefix(strings.TrimSpace(line), "RUN ")
-			packages = append(packages, r.extractFromCICD(runContent)...)
+			found := r.extractFromCICD(runContent)
+			shiftEvidence(found, indexFrom(line, runContent, lineStart))
+			packages = append(packages, found...)
 		}
 
 		if strings.Contains(line, "$(NPM)") || strings.Contains(line, "$(YARN)") || strings.Contains(line, "$(NPX)") {
@@ -949,7 +1331,14 @@ func (r *Runner) extractFromCICD(content string) []Package {
 			makefileContent = strings.ReplaceAll(makefileContent, "$(YARN)", "yarn")
 			makefileContent = strings.ReplaceAll(makefileContent, "$(NPX)", "npx")
 
-			packages = append(packages, r.extractFromCICD(makefileContent)...)
+			// the expanded line no longer lines up with the original
+			found := r.extractFromCICD(makefileContent)
+			for i := range found {
+				for j := range found[i].Evidence {
+					found[i].Evidence[j].Offset = indexFrom(line, found[i].FullName(), lineStart)
+				}
+			}
+			packages = append(packages, found...)
 		}
 	}
 
@@ -960,59 +1349,63 @@ func (r *Runner) extractFromDocumentation(content string) []Package {
 	var packages []Package
 
 	codeBlockRegex := regexp.MustCompile("(?s)```[a-zA-Z]*\n(.*?)\n```")
-	codeBlocks := codeBlockRegex.FindAllStringSubmatch(content, -1)
+	codeBlocks := codeBlockRegex.FindAllStringSubmatchIndex(content, -1)
 	for _, block := range codeBlocks {
-		if len(block) > 1 {
-			blockContent := block[1]
-			packages = append(packages, r.extractFromJavaScript(blockContent)...)
-			packages = append(packages, r.extractFromCICD(blockContent)...)
-		}
-	}
-
-	contentWithoutBlocks := codeBlockRegex.ReplaceAllString(content, "")
-
-	docPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`npm\s+install\s+(?:-[gDS]\s+|--save-dev\s+|--save\s+)?(.+?)(?:\n|$)`),
-		regexp.MustCompile(`yarn\s+add\s+(?:--dev\s+)?(.+?)(?:\n|$)`),
-		regexp.MustCompile(`pnpm\s+(?:add|install)\s+(.+?)(?:\n|$)`),
-		regexp.MustCompile(`npx\s+([a-zA-Z0-9@/_-]+)`),
-		regexp.MustCompile(`yarn\s+create\s+([a-zA-Z0-9@/_-]+)`),
-		regexp.MustCompile(`npm\s+create\s+([a-zA-Z0-9@/_-]+)`),
-	}
-
-	for _, pattern := range docPatterns {
-		matches := pattern.FindAllStringSubmatch(contentWithoutBlocks, -1)
+		blockContent := content[block[2]:block[3]]
+		found := r.extractFromJavaScript(blockContent)
+		found = append(found, r.extractFromCICD(blockContent)...)
+		shiftEvidence(found, block[2])
+		packages = append(packages, found...)
+	}
+
+	// code blocks are blanked out rather than removed to keep offsets intact
+	contentWithoutBlocks := codeBlockRegex.ReplaceAllStringFunc(content, blankOut)
+
+	docPatterns := []struct {
+		method  string
+		pattern *regexp.Regexp
+	}{
+		{"doc-npm-install", regexp.MustCompile(`npm\s+install\s+(?:-[gDS]\s+|--save-dev\s+|--save\s+)?(.+?)(?:\n|$)`)},
+		{"doc-yarn-add", regexp.MustCompile(`yarn\s+add\s+(?:--dev\s+)?(.+?)(?:\n|$)`)},
+		{"doc-pnpm-add", regexp.MustCompile(`pnpm\s+(?:add|install)\s+(.+?)(?:\n|$)`)},
+		{"doc-npx", regexp.MustCompile(`npx\s+([a-zA-Z0-9@/_-]+)`)},
+		{"doc-yarn-create", regexp.MustCompile(`yarn\s+create\s+([a-zA-Z0-9@/_-]+)`)},
+		{"doc-npm-create", regexp.MustCompile(`npm\s+create\s+([a-zA-Z0-9@/_-]+)`)},
+	}
+
+	for _, p := range docPatterns {
+		matches := p.pattern.FindAllStringSubmatchIndex(contentWithoutBlocks, -1)
 		for _, match := range matches {
-			if len(match) > 1 {
-				parts := strings.Fields(match[1])
-				for _, part := range parts {
-					part = strings.TrimSpace(part)
-					part = strings.Trim(part, "`\"'")
-						if strings.Contains(part, "@") && !strings.HasPrefix(part, "@") {
-						part = strings.Split(part, "@")[0]
-					}
-					if part != "" && !strings.HasPrefix(part, "-") &&
-						!r.isBuiltinModule(part) && r.looksLikePackageName(part) {
-						packages = append(packages, r.createPackageFromName(part))
-					}
+			group := contentWithoutBlocks[match[2]:match[3]]
+			parts := strings.Fields(group)
+			for _, part := range parts {
+				part = strings.TrimSpace(part)
+				part = strings.Trim(part, "`\"'")
+				if strings.HasPrefix(part, "-") {
+					continue
+				}
+				if name, ok := r.specifierPackageName(part); ok && r.looksLikePackageName(name) {
+					packages = append(packages, r.createPackageFromName(name, p.method, indexFrom(group, part, match[2])))
 				}
 			}
 		}
 	}
 
 	inlineCodeRegex := regexp.MustCompile("`([a-zA-Z0-9@/_-]+)`")
-	inlineMatches := inlineCodeRegex.FindAllStringSubmatch(contentWithoutBlocks, -1)
+	inlineMatches := inlineCodeRegex.FindAllStringSubmatchIndex(contentWithoutBlocks, -1)
 	for _, match := range inlineMatches {
-		if len(match) > 1 && r.looksLikePackageName(match[1]) && !r.isBuiltinModule(match[1]) {
-			packages = append(packages, r.createPackageFromName(match[1]))
+		name := contentWithoutBlocks[match[2]:match[3]]
+		if r.looksLikePackageName(name) && !r.isBuiltinModule(name) {
+			packages = append(packages, r.createPackageFromName(name, "doc-inline-code", match[2]))
 		}
 	}
 
 	jsonExampleRegex := regexp.MustCompile(`"([a-zA-Z0-9@/_-]+)":\s*"[\^~]?[\d.]+.*?"`)
-	jsonMatches := jsonExampleRegex.FindAllStringSubmatch(contentWithoutBlocks, -1)
+	jsonMatches := jsonExampleRegex.FindAllStringSubmatchIndex(contentWithoutBlocks, -1)
 	for _, match := range jsonMatches {
-		if len(match) > 1 && r.looksLikePackageName(match[1]) && !r.isBuiltinModule(match[1]) {
-			packages = append(packages, r.createPackageFromName(match[1]))
+		name := contentWithoutBlocks[match[2]:match[3]]
+		if r.looksLikePackageName(name) && !r.isBuiltinModule(name) {
+			packages = append(packages, r.createPackageFromName(name, "doc-json-example", match[2]))
 		}
 	}
 
@@ -1038,46 +1431,26 @@ func (r *Runner) looksLikePackageName(name string) bool {
 	return hasLetters
 }
 
-func (r *Runner) createPackageFromName(name string) Package {
-	name = strings.TrimSpace(name)
-
-	if strings.HasPrefix(name, "@") && strings.Contains(name, "/") {
-		return Package{
-			Name:      name,
-			Namespace: "",
-		}
-	}
-
-	return Package{
-		Name:      name,
-		Namespace: "",
-	}
+// createScopeFromName creates a scope finding for a scope found by the given
+// detection method at offset in the content. The leading @ is optional
+func (r *Runner) createScopeFromName(scope, method string, offset int) Package {
+	scope = "@" + strings.TrimPrefix(strings.TrimSpace(scope), "@")
+	return Package{Namespace: scope, Evidence: []Evidence{{Method: method, Offset: offset}}}
 }
 
-func (r *Runner) isBuiltinModule(name string) bool {
-	builtins := map[string]bool{
-		"fs": true, "path": true, "http": true, "https": true, "url": true,
-		"crypto": true, "os": true, "util": true, "events": true, "stream": true,
-		"buffer": true, "process": true, "child_process": true, "cluster": true,
-		"net": true, "tls": true, "dns": true, "zlib": true, "readline": true,
-	}
-	return builtins[name]
-}
-
-func (r *Runner) isPackageClaimed(packageName string) bool {
-	url := fmt.Sprintf("https://registry.npmjs.com/%s", packageName)
+// createPackageFromName creates a package found by the given detection
+// method at offset in the content, or at -1 if the offset is unknown
+func (r *Runner) createPackageFromName(name, method string, offset int) Package {
+	name = strings.TrimSpace(name)
 
-	resp, err := r.client.Head(url)
+	pkg, err := ParsePackageName(name)
 	if err != nil {
-		log.Warnf("Error: %v", err)
-		return false
+		// kept unparsed so extractPackages can report and drop it
+		pkg = Package{Name: name}
 	}
+	pkg.Evidence = []Evidence{{Method: method, Offset: offset}}
 
-	if resp.StatusCode == http.StatusOK {
-		return true
-	} else {
-		return false
-	}
+	return pkg
 }
 
 func (r *Runner) getDelay() time.Duration {
@@ -1120,7 +1493,6 @@ func normalizeURLString(rawURL string) (normalizedURL string, err error) {
 	return normalizedURL, err
 }
 
-
 func trimURLParams(url string) string {
 	if strings.Contains(url, "?") {
 		return strings.Split(url, "?")[0]
@@ -1135,7 +1507,6 @@ func (r *Runner) getLastResolver() string {
 	return "system"
 }
 
-
 // SetLogLevel configures logger verbosity
 func SetLogLevel(options *Options) {
 	if options.Verbose {
@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newSlowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return
+		}
+		fmt.Fprint(w, `{"dependencies": {"express": "^4.18.0"}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func collectResults(runner *Runner) <-chan []Result {
+	out := make(chan []Result, 1)
+	go func() {
+		var results []Result
+		for result := range runner.Results {
+			results = append(results, result)
+		}
+		out <- results
+	}()
+	return out
+}
+
+func TestRunContextTimeoutStartsWithRequest(t *testing.T) {
+	server := newSlowServer(t, 400*time.Millisecond)
+
+	runner := newStubRegistry(t, map[string]int{"/express": http.StatusOK})
+	runner.Options.Concurrency = 1
+	runner.Options.Timeout = 1
+	results := collectResults(runner)
+
+	// queued requests would time out if their clock started before they ran
+	runner.RunContext(context.Background(),
+		server.URL+"/a/package.json", server.URL+"/b/package.json",
+		server.URL+"/c/package.json", server.URL+"/d/package.json")
+	runner.Close()
+
+	received := <-results
+	if len(received) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(received))
+	}
+	for _, result := range received {
+		if result.Error != nil || len(result.Packages) != 1 {
+			t.Errorf("%s: expected one package, got %d (error: %v)", result.RequestURL, len(result.Packages), result.Error)
+		}
+	}
+}
+
+func TestRunContextCancellation(t *testing.T) {
+	server := newSlowServer(t, 10*time.Second)
+
+	runner := newStubRegistry(t, nil)
+	runner.Options.Concurrency = 2
+	results := collectResults(runner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	runner.RunContext(ctx,
+		server.URL+"/a/package.json", server.URL+"/b/package.json",
+		server.URL+"/c/package.json", server.URL+"/d/package.json")
+	runner.Close()
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected RunContext to return soon after cancellation, took %v", elapsed)
+	}
+	if received := <-results; len(received) != 0 {
+		t.Errorf("expected results of aborted requests to be dropped, got %v", received)
+	}
+}
+
+func TestRunContextCancellationKeepsFinishedResults(t *testing.T) {
+	fast := newSlowServer(t, 0)
+	slow := newSlowServer(t, 10*time.Second)
+
+	runner := newStubRegistry(t, map[string]int{"/express": http.StatusOK})
+	runner.Options.Concurrency = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.RunContext(ctx, fast.URL+"/package.json", slow.URL+"/package.json")
+		close(done)
+	}()
+
+	// the finished result is only read once the scan has been cancelled
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	results := collectResults(runner)
+	<-done
+	runner.Close()
+
+	received := <-results
+	if len(received) != 1 || received[0].RequestURL != fast.URL+"/package.json" {
+		t.Fatalf("expected the finished result only, got %v", received)
+	}
+	if len(received[0].Packages) != 1 {
+		t.Errorf("expected one package, got %v", received[0].Packages)
+	}
+}
+
+func TestRunContextCancellationWithoutReader(t *testing.T) {
+	server := newSlowServer(t, 0)
+
+	runner := newStubRegistry(t, map[string]int{"/express": http.StatusOK})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.RunContext(ctx, server.URL+"/a/package.json", server.URL+"/b/package.json")
+		close(done)
+	}()
+
+	// nothing reads Results, so finished scans wait to send until cancelled
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(resultGracePeriod + 2*time.Second):
+		t.Fatalf("expected RunContext to return after cancellation without a reader")
+	}
+	runner.Close()
+}
+
+func TestRunContextConcurrentCalls(t *testing.T) {
+	server := newSlowServer(t, 10*time.Millisecond)
+
+	runner := newStubRegistry(t, map[string]int{"/express": http.StatusOK})
+	results := collectResults(runner)
+
+	// overlapping calls share the visited state, so each url is scanned once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			runner.RunContext(context.Background(),
+				server.URL+"/shared/package.json", fmt.Sprintf("%s/%d/package.json", server.URL, i))
+		}(i)
+	}
+	wg.Wait()
+	runner.Close()
+	runner.Close()
+
+	if got := len(<-results); got != 9 {
+		t.Errorf("expected 9 results, got %d", got)
+	}
+
+	// scans after Close are ignored instead of sending on a closed channel
+	runner.RunContext(context.Background(), server.URL+"/late/package.json")
+}
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// sourceMappingURLRegex matches the source map comment of scripts
+// (//# sourceMappingURL=...) and stylesheets (/*# sourceMappingURL=... */),
+// including the deprecated //@ form
+var sourceMappingURLRegex = regexp.MustCompile(`(?://|/\*)[#@]\s*sourceMappingURL=([^\s'"*]+)`)
+
+// inlineSourceMapRegex matches a source map comment whose map is inlined
+// as a data: URI
+var inlineSourceMapRegex = regexp.MustCompile(`(?://|/\*)[#@]\s*sourceMappingURL=(data:[^\s'"*]+)`)
+
+// scriptExtensions are the extensions of files that may reference a source map
+var scriptExtensions = []string{".js", ".mjs", ".cjs", ".css"}
+
+// isScriptOrStylesheet reports whether a response is JavaScript or CSS,
+// judging by its media type or, if it is missing or generic, the URL
+func (r *Runner) isScriptOrStylesheet(url, contentType string) bool {
+	switch {
+	case strings.Contains(contentType, "javascript"), contentType == "text/css":
+		return true
+	case contentType != "" && contentType != "text/plain" && contentType != "application/octet-stream":
+		return false
+	}
+
+	lowerURL := strings.ToLower(trimURLParams(url))
+	for _, ext := range scriptExtensions {
+		if strings.HasSuffix(lowerURL, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceMapURL returns the URL of the source map of a script or stylesheet,
+// taken from the SourceMap or X-SourceMap header or else the last
+// sourceMappingURL comment, resolved against the URL the response came from.
+// Inline data: maps and references that aren't http(s) URLs are skipped
+func sourceMapURL(header http.Header, body []byte, base *url.URL) (string, bool) {
+	ref := header.Get("SourceMap")
+	if ref == "" {
+		ref = header.Get("X-SourceMap")
+	}
+	if ref == "" {
+		matches := sourceMappingURLRegex.FindAllSubmatch(body, -1)
+		if len(matches) == 0 {
+			return "", false
+		}
+		ref = string(matches[len(matches)-1][1])
+	}
+
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(strings.ToLower(ref), "data:") {
+		return "", false
+	}
+
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		refURL = base.ResolveReference(refURL)
+	}
+	if refURL.Scheme != "http" && refURL.Scheme != "https" {
+		return "", false
+	}
+	return refURL.String(), true
+}
+
+// extractFromInlineSourceMaps finds the packages of source maps inlined in
+// a script or stylesheet as data: URIs. Packages named by the paths of the
+// map's sources are located at the data URI, packages found in its
+// sourcesContent in the original source files
+func (r *Runner) extractFromInlineSourceMaps(content string) []Package {
+	var packages []Package
+
+	for _, match := range inlineSourceMapRegex.FindAllStringSubmatchIndex(content, -1) {
+		data, ok := decodeDataURI(content[match[2]:match[3]])
+		if !ok {
+			continue
+		}
+
+		found := r.extractFromSourceMap(string(data))
+		for i := range found {
+			for j := range found[i].Evidence {
+				if e := &found[i].Evidence[j]; e.Source == "" {
+					e.Offset = match[2]
+				}
+			}
+		}
+		packages = append(packages, found...)
+	}
+
+	return packages
+}
+
+// decodeDataURI returns the data of a data: URI, which is either base64
+// (data:application/json;base64,...) or percent encoded
+func decodeDataURI(uri string) ([]byte, bool) {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, false
+	}
+
+	if !strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		unescaped, err := url.PathUnescape(data)
+		return []byte(unescaped), err == nil
+	}
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(data); err == nil {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
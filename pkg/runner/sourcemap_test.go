@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSourceMapURL(t *testing.T) {
+	base, _ := url.Parse("https://example.com/static/js/app.js")
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		url    string
+		found  bool
+	}{
+		{"comment", nil, "x();\n//# sourceMappingURL=app.js.map", "https://example.com/static/js/app.js.map", true},
+		{"legacy comment", nil, "x();\n//@ sourceMappingURL=app.js.map", "https://example.com/static/js/app.js.map", true},
+		{"css comment", nil, "a{}\n/*# sourceMappingURL=app.css.map */", "https://example.com/static/js/app.css.map", true},
+		{"last comment", nil, "//# sourceMappingURL=a.js.map\nb();\n//# sourceMappingURL=b.js.map\n", "https://example.com/static/js/b.js.map", true},
+		{"absolute path", nil, "//# sourceMappingURL=/maps/app.js.map", "https://example.com/maps/app.js.map", true},
+		{"header", http.Header{"Sourcemap": {"/maps/app.js.map"}}, "//# sourceMappingURL=app.js.map", "https://example.com/maps/app.js.map", true},
+		{"x header", http.Header{"X-Sourcemap": {"https://cdn.example.com/app.js.map"}}, "", "https://cdn.example.com/app.js.map", true},
+		{"data uri", nil, "//# sourceMappingURL=data:application/json;base64,e30=", "", false},
+		{"file url", nil, "//# sourceMappingURL=file:///tmp/app.js.map", "", false},
+		{"none", nil, "x();", "", false},
+	}
+
+	for _, tt := range tests {
+		mapURL, ok := sourceMapURL(tt.header, []byte(tt.body), base)
+		if ok != tt.found || mapURL != tt.url {
+			t.Errorf("%s: expected %q %v, got %q %v", tt.name, tt.url, tt.found, mapURL, ok)
+		}
+	}
+}
+
+func TestRunFollowsSourceMaps(t *testing.T) {
+	var mapRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/static/app.js", "/static/other.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, "console.log(1);\n//# sourceMappingURL=app.js.map\n")
+		case "/static/vendor.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Header().Set("SourceMap", "/maps/vendor.js.map")
+			fmt.Fprint(w, "console.log(2);\n")
+		case "/static/app.js.map":
+			mapRequests.Add(1)
+			fmt.Fprint(w, `{"version":3,"sources":["webpack://app/./node_modules/company-app-internals/index.js"]}`)
+		case "/maps/vendor.js.map":
+			fmt.Fprint(w, `{"version":3,"sources":["webpack://app/./node_modules/company-vendor-internals/index.js"]}`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	runner := newStubRegistry(t, nil)
+	runner.Options.Concurrency = 1
+	results := collectResults(runner)
+
+	runner.RunContext(context.Background(), server.URL+"/static/app.js", server.URL+"/static/other.js", server.URL+"/static/vendor.js")
+	runner.Close()
+
+	referrers := make(map[string]Result)
+	for _, result := range <-results {
+		referrers[result.RequestURL] = result
+	}
+
+	// the shared map is scanned once and credited to both scripts
+	tests := []struct {
+		mapURL    string
+		referrers []string
+		pkg       string
+	}{
+		{server.URL + "/static/app.js.map", []string{server.URL + "/static/app.js", server.URL + "/static/other.js"}, "company-app-internals"},
+		{server.URL + "/maps/vendor.js.map", []string{server.URL + "/static/vendor.js"}, "company-vendor-internals"},
+	}
+
+	for _, tt := range tests {
+		result, ok := referrers[tt.mapURL]
+		if !ok {
+			t.Errorf("expected %s to be scanned, got %v", tt.mapURL, referrers)
+			continue
+		}
+		if !reflect.DeepEqual(result.Referrers, tt.referrers) {
+			t.Errorf("%s: expected referrers %v, got %v", tt.mapURL, tt.referrers, result.Referrers)
+		}
+		if _, ok := findPackage(result.Packages, tt.pkg); !ok {
+			t.Errorf("%s: expected %s, got %v", tt.mapURL, tt.pkg, result.Packages)
+		}
+	}
+
+	if n := mapRequests.Load(); n != 1 {
+		t.Errorf("expected the shared source map to be fetched once, got %d", n)
+	}
+	if len(referrers) != 5 {
+		t.Errorf("expected 5 results, got %d", len(referrers))
+	}
+}
+
+func TestInlineSourceMap(t *testing.T) {
+	sourceMap := `{"version":3,"sources":["webpack://app/./node_modules/company-inline-internals/index.js","webpack://app/./src/index.js"],` +
+		`"sourcesContent":["","import 'company-inline-helper';"]}`
+	content := "x();\n//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(sourceMap)) + "\n"
+
+	runner := NewRunner()
+	packages := runner.extractPackages("app.js", "", []byte(content))
+
+	tests := []struct {
+		pkg    string
+		source string
+		line   int
+		column int
+	}{
+		{"company-inline-internals", "app.js", 2, 22},
+		{"company-inline-helper", "webpack://app/./src/index.js", 1, 9},
+	}
+
+	for _, tt := range tests {
+		pkg, ok := findPackage(packages, tt.pkg)
+		if !ok {
+			t.Errorf("%s not detected in %v", tt.pkg, packages)
+			continue
+		}
+		if e := pkg.Evidence[0]; e.Source != tt.source || e.Line != tt.line || e.Column != tt.column {
+			t.Errorf("%s: expected %s:%d:%d, got %s:%d:%d", tt.pkg, tt.source, tt.line, tt.column, e.Source, e.Line, e.Column)
+		}
+	}
+}
+
+func TestIndexSourceMap(t *testing.T) {
+	content := `{"version":3,"sections":[` +
+		`{"offset":{"line":0,"column":0},"map":{"version":3,"sources":["webpack://app/./node_modules/company-first-section/index.js"]}},` +
+		`{"offset":{"line":100,"column":0},"map":{"version":3,"sources":["src/a.js"],"sourcesContent":["require('company-second-section')"]}}]}`
+
+	runner := NewRunner()
+	packages := runner.extractPackages("app.js.map", "", []byte(content))
+
+	for _, name := range []string{"company-first-section", "company-second-section"} {
+		if _, ok := findPackage(packages, name); !ok {
+			t.Errorf("expected %s to be detected, got %v", name, packages)
+		}
+	}
+}
+
+func TestDecodeDataURI(t *testing.T) {
+	tests := []struct {
+		uri   string
+		data  string
+		valid bool
+	}{
+		{"data:application/json;base64,eyJhIjoxfQ==", `{"a":1}`, true},
+		{"data:application/json;charset=utf-8;base64,eyJhIjoxfQ", `{"a":1}`, true},
+		{"data:application/json,%7B%22a%22%3A1%7D", `{"a":1}`, true},
+		{"data:application/json;base64,!!!", "", false},
+		{"data:application/json", "", false},
+	}
+
+	for _, tt := range tests {
+		data, ok := decodeDataURI(tt.uri)
+		if ok != tt.valid || string(data) != tt.data {
+			t.Errorf("%q: expected %q %v, got %q %v", tt.uri, tt.data, tt.valid, data, ok)
+		}
+	}
+}
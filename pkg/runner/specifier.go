@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"regexp"
+	"strings"
+)
+
+// schemeRegex matches specifiers with a protocol or URL scheme, such as
+// node:fs, file:../lib, workspace:*, git+ssh://..., github:user/repo or
+// https://cdn.example.com/lib.js
+var schemeRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// NormalizeSpecifier returns the name of the registry package that a module
+// specifier or install argument refers to. Subpaths, versions and queries
+// are stripped, so "lodash/fp", "@babel/core/lib/x" and "react@18.2.0"
+// become "lodash", "@babel/core" and "react". npm: aliases resolve to the
+// aliased package ("npm:react@18" and "my-react@npm:react@18" become
+// "react"), while a name with a workspace:, file:, link: or git version
+// keeps its name, since it's a package the project refers to by name.
+//
+// It reports false for specifiers that don't name a registry package:
+// relative, absolute and home paths, subpath imports (#internal), URLs and
+// protocol specifiers such as node:fs, file:../lib or git+https://...
+func NormalizeSpecifier(spec string) (string, bool) {
+	spec = strings.TrimSpace(spec)
+
+	// name@npm:real@version aliases resolve to the real package
+	if i := strings.Index(spec, "@npm:"); i > 0 {
+		spec = spec[i+1:]
+	}
+	spec = strings.TrimPrefix(spec, "npm:")
+
+	// ~name is how Sass and webpack loaders refer to node_modules
+	if strings.HasPrefix(spec, "~") && !strings.HasPrefix(spec, "~/") {
+		spec = spec[1:]
+	}
+
+	if spec == "" || strings.ContainsAny(spec[:1], "./~#\\") || schemeRegex.MatchString(spec) {
+		return "", false
+	}
+
+	if i := strings.IndexAny(spec, "?#"); i != -1 {
+		spec = spec[:i]
+	}
+
+	name := spec
+	if strings.HasPrefix(name, "@") {
+		parts := strings.SplitN(name, "/", 3)
+		if len(parts) < 2 {
+			return "", false
+		}
+		name = parts[0] + "/" + parts[1]
+	} else {
+		name = strings.SplitN(name, "/", 2)[0]
+	}
+
+	// strip the version, keeping the @ of a scope
+	if i := strings.LastIndex(name, "@"); i > 0 {
+		name = name[:i]
+	}
+
+	if name == "" || strings.HasSuffix(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// specifierPackageName returns the package a module specifier refers to, or
+// false if it doesn't name a registry package or names a Node.js builtin
+func (r *Runner) specifierPackageName(spec string) (string, bool) {
+	name, ok := NormalizeSpecifier(spec)
+	if !ok || r.isBuiltinModule(name) {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,88 @@
+package runner
+
+import "testing"
+
+func TestNormalizeSpecifier(t *testing.T) {
+	tests := []struct {
+		spec  string
+		name  string
+		valid bool
+	}{
+		{"lodash", "lodash", true},
+		{"lodash/fp", "lodash", true},
+		{"@babel/core", "@babel/core", true},
+		{"@babel/core/lib/x", "@babel/core", true},
+		{"react@18.2.0", "react", true},
+		{"@scope/pkg@^1.0.0", "@scope/pkg", true},
+		{"lodash@4.17.21/lodash.min.js", "lodash", true},
+		{"jquery/3.6.0/jquery.min.js", "jquery", true},
+		{"raw-loader?esModule=false", "raw-loader", true},
+		{"~bootstrap/scss/bootstrap", "bootstrap", true},
+		{"npm:react@18", "react", true},
+		{"npm:@scope/pkg", "@scope/pkg", true},
+		{"my-react@npm:react@^18", "react", true},
+		{"internal-ui@workspace:*", "internal-ui", true},
+		{"local-lib@file:../lib", "local-lib", true},
+		{"linked@link:../linked", "linked", true},
+		{"forked@git+https://github.com/a/b.git", "forked", true},
+		{" padded ", "padded", true},
+		{"./utils", "", false},
+		{"../lib/x", "", false},
+		{"/abs/path", "", false},
+		{"~/home", "", false},
+		{"#internal", "", false},
+		{"//cdn.example.com/lib.js", "", false},
+		{"https://cdn.example.com/lib.js", "", false},
+		{"node:fs", "", false},
+		{"node:test", "", false},
+		{"workspace:*", "", false},
+		{"file:../lib", "", false},
+		{"link:../linked", "", false},
+		{"git+ssh://git@github.com/a/b.git", "", false},
+		{"github:user/repo", "", false},
+		{"@scope", "", false},
+		{"@scope/", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		name, ok := NormalizeSpecifier(tt.spec)
+		if ok != tt.valid || name != tt.name {
+			t.Errorf("%q: expected %q %v, got %q %v", tt.spec, tt.name, tt.valid, name, ok)
+		}
+	}
+}
+
+func TestSpecifiersAreNormalized(t *testing.T) {
+	runner := NewRunner()
+	content := `import fp from "lodash/fp";
+import { x } from "@babel/core/lib/x";
+import utils from "./utils";
+import fs from "node:fs";
+import { readFile } from "fs/promises";
+const remote = await import("https://cdn.example.com/remote.js");`
+
+	packages := runner.extractPackages("app.js", "", []byte(content))
+
+	for _, name := range []string{"lodash", "@babel/core"} {
+		if _, ok := findPackage(packages, name); !ok {
+			t.Errorf("expected %s to be detected, got %v", name, packages)
+		}
+	}
+	if len(packages) != 2 {
+		t.Errorf("expected only lodash and @babel/core, got %v", packages)
+	}
+}
+
+func TestPackageJSONAliasIsResolved(t *testing.T) {
+	runner := NewRunner()
+	content := `{"dependencies": {"my-react": "npm:react@^18.2.0"}}`
+
+	packages := runner.extractPackages("package.json", "", []byte(content))
+
+	for _, name := range []string{"my-react", "react"} {
+		if _, ok := findPackage(packages, name); !ok {
+			t.Errorf("expected %s to be detected, got %v", name, packages)
+		}
+	}
+}
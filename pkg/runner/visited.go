@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"bufio"
+	"hash/fnv"
+	"os"
+	"sync"
+
+	"github.com/root4loot/goutils/log"
+)
+
+// VisitedStore records which URLs and files have been scanned so each is
+// only scanned once. Implementations must be safe for concurrent use
+type VisitedStore interface {
+	// Visit marks key as visited and reports whether this is the first visit
+	Visit(key string) bool
+	// Has reports whether key has been visited
+	Has(key string) bool
+}
+
+// MemoryVisited is an in-memory VisitedStore
+type MemoryVisited struct {
+	mu      sync.Mutex
+	visited map[string]bool
+}
+
+// NewMemoryVisited creates an empty in-memory VisitedStore
+func NewMemoryVisited() *MemoryVisited {
+	return &MemoryVisited{visited: make(map[string]bool)}
+}
+
+// Visit marks key as visited and reports whether this is the first visit
+func (m *MemoryVisited) Visit(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.visited[key] {
+		return false
+	}
+	m.visited[key] = true
+	return true
+}
+
+// Has reports whether key has been visited
+func (m *MemoryVisited) Has(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.visited[key]
+}
+
+// FileVisited is a VisitedStore backed by a file, for target lists too large
+// to keep in memory or scans that should resume where they left off. Keys are
+// appended to the file as they are visited and only a 64-bit hash of each key
+// is kept in memory
+type FileVisited struct {
+	mu     sync.Mutex
+	hashes map[uint64]struct{}
+	file   *os.File
+	closed bool
+}
+
+// NewFileVisited opens or creates the file at path, loading previously
+// visited keys from it
+func NewFileVisited(path string) (*FileVisited, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FileVisited{
+		hashes: make(map[uint64]struct{}),
+		file:   file,
+	}
+
+	var last string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		last = scanner.Text()
+		f.hashes[hashKey(last)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// a file cut off mid-line must not run on into the next key
+	if info, err := file.Stat(); err == nil && info.Size() > 0 && len(last) > 0 {
+		buf := make([]byte, 1)
+		if _, err := file.ReadAt(buf, info.Size()-1); err == nil && buf[0] != '\n' {
+			if _, err := file.WriteString("\n"); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// Visit marks key as visited and reports whether this is the first visit
+func (f *FileVisited) Visit(key string) bool {
+	h := hashKey(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.hashes[h]; ok {
+		return false
+	}
+	f.hashes[h] = struct{}{}
+	if !f.closed {
+		// written at once, so an exit at any point loses no earlier keys
+		if _, err := f.file.WriteString(key + "\n"); err != nil {
+			log.Warnf("Could not save visited %s: %v", key, err)
+		}
+	}
+	return true
+}
+
+// Has reports whether key has been visited
+func (f *FileVisited) Has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.hashes[hashKey(key)]
+	return ok
+}
+
+// Close closes the file. Keys visited afterwards are only kept in memory.
+// Calling Close more than once has no effect
+func (f *FileVisited) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.file.Close()
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
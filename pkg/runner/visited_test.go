@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMemoryVisitedConcurrent(t *testing.T) {
+	visited := NewMemoryVisited()
+
+	var first atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if visited.Visit("https://example.com/app.js") {
+				first.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if first.Load() != 1 {
+		t.Errorf("expected exactly one first visit, got %d", first.Load())
+	}
+	if !visited.Has("https://example.com/app.js") || visited.Has("https://example.com/other.js") {
+		t.Errorf("Has does not match visited keys")
+	}
+}
+
+func TestFileVisitedPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "visited.txt")
+
+	visited, err := NewFileVisited(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if !visited.Visit(fmt.Sprintf("https://example.com/%d.js", i)) {
+			t.Errorf("expected first visit of %d.js", i)
+		}
+	}
+	if visited.Visit("https://example.com/0.js") {
+		t.Errorf("expected repeated visit to be rejected")
+	}
+	if err := visited.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileVisited(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if !reopened.Has("https://example.com/2.js") {
+		t.Errorf("expected visited keys to be loaded from %s", path)
+	}
+	if reopened.Visit("https://example.com/1.js") {
+		t.Errorf("expected key from previous run to be visited")
+	}
+	if !reopened.Visit("https://example.com/3.js") {
+		t.Errorf("expected new key to be unvisited")
+	}
+}
+
+func TestFileVisitedWritesEachKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "visited.txt")
+	if err := os.WriteFile(path, []byte("https://example.com/0.js\nhttps://exa"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	visited, err := NewFileVisited(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer visited.Close()
+	visited.Visit("https://example.com/1.js")
+
+	// keys are on disk before Close, and not joined to a cut off line
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://example.com/0.js\nhttps://exa\nhttps://example.com/1.js\n"; string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+}
+
+func TestRunOnlyVisitsScannedURLs(t *testing.T) {
+	server := newSlowServer(t, 0)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	runner := newStubRegistry(t, map[string]int{"/express": http.StatusOK})
+	results := collectResults(runner)
+
+	runner.RunContext(context.Background(), server.URL+"/package.json", closed.URL+"/package.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runner.RunContext(ctx, server.URL+"/late/package.json")
+	runner.Close()
+	<-results
+
+	if !runner.Visited.Has(server.URL + "/package.json") {
+		t.Errorf("expected scanned url to be visited")
+	}
+	if runner.Visited.Has(closed.URL + "/package.json") {
+		t.Errorf("expected failed url not to be visited")
+	}
+	if runner.Visited.Has(server.URL + "/late/package.json") {
+		t.Errorf("expected cancelled url not to be visited")
+	}
+}
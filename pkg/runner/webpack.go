@@ -0,0 +1,291 @@
+package runner
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// webpackChunkURLRegex matches the start of the function a webpack 5
+	// runtime builds chunk filenames with (__webpack_require__.u = e => ...),
+	// or of webpack 4's jsonpScriptSrc (function(e){return r.p + ...}),
+	// capturing the runtime object and the name of the chunk id parameter
+	webpackChunkURLRegex = regexp.MustCompile(`([\w$]+)\.u\s*=\s*(?:function\s*\(\s*([\w$]+)\s*\)\s*\{\s*return\s*|\(?\s*([\w$]+)\s*\)?\s*=>\s*)|function\s+[\w$]+\s*\(\s*([\w$]+)\s*\)\s*\{\s*return\s*([\w$]+)\.p\s*\+\s*`)
+
+	// webpackChunkLoadRegex matches chunk loads (__webpack_require__.e(42)),
+	// which name chunks when the filename builder doesn't list them
+	webpackChunkLoadRegex = regexp.MustCompile(`[\w$]+\.e\(\s*(\d+|"[^"]+"|'[^']+')\s*\)`)
+)
+
+// webpackTerm is a part of a chunk filename expression: a string, the chunk
+// id, or a lookup of the chunk id in a map of chunk names or hashes
+type webpackTerm struct {
+	literal  string
+	id       bool
+	lookup   map[string]string
+	fallback bool // whether the id is used if the lookup has no entry ({...}[e]||e)
+}
+
+// webpackChunkURLs reconstructs the URLs of the chunks a webpack runtime
+// loads lazily, from its chunk filename builder and public path, resolved
+// against the URL of the script. Chunks are named by the maps of the
+// builder or by the chunk loads in the script
+func webpackChunkURLs(content string, scriptURL *url.URL) []string {
+	var urls []string
+	seen := make(map[string]bool)
+
+	var loaded []string
+	for _, match := range webpackChunkLoadRegex.FindAllStringSubmatch(content, -1) {
+		loaded = append(loaded, strings.Trim(match[1], `"'`))
+	}
+
+	for _, match := range webpackChunkURLRegex.FindAllStringSubmatchIndex(content, -1) {
+		runtime, param := "", ""
+		for i := 2; i < len(match); i += 2 {
+			if match[i] == -1 {
+				continue
+			}
+			if group := content[match[i]:match[i+1]]; i == 2 || i == 10 {
+				runtime = group
+			} else {
+				param = group
+			}
+		}
+		base := webpackPublicPath(content, runtime, scriptURL)
+
+		body, ok := webpackArrowBody(content[match[1]:])
+		if !ok {
+			continue
+		}
+		terms, ok := parseWebpackTerms(body, param)
+		if !ok {
+			continue
+		}
+
+		for _, id := range webpackChunkIDs(terms, loaded) {
+			filename, ok := webpackChunkFilename(terms, id)
+			if !ok || !strings.HasSuffix(filename, ".js") {
+				continue
+			}
+
+			ref, err := url.Parse(filename)
+			if err != nil {
+				continue
+			}
+			if base != nil {
+				ref = base.ResolveReference(ref)
+			}
+			if u := ref.String(); !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	return urls
+}
+
+// webpackArrowBody returns the expression an arrow function returns, which
+// follows "return" if the function has a block body, as in unminified
+// runtimes (__webpack_require__.u = (chunkId) => { return "" + chunkId ...})
+func webpackArrowBody(s string) (string, bool) {
+	block, ok := strings.CutPrefix(strings.TrimLeft(s, " \t\r\n"), "{")
+	if !ok {
+		return s, true
+	}
+	expr, ok := strings.CutPrefix(strings.TrimLeft(block, " \t\r\n"), "return")
+	if !ok || isIdentByte(expr, 0) {
+		return "", false
+	}
+	return expr, true
+}
+
+// webpackPublicPath returns the URL chunks are loaded relative to: the
+// public path set on the runtime object, either outright
+// (__webpack_require__.p = "/static/") or relative to the script's own URL
+// (r.p = e + "../"), resolved against the script URL. Without one chunks
+// are loaded relative to the script
+func webpackPublicPath(content, runtime string, scriptURL *url.URL) *url.URL {
+	if scriptURL == nil {
+		return nil
+	}
+
+	publicPathRegex, err := regexp.Compile(`(?:^|[^\w$.])` + regexp.QuoteMeta(runtime) + `\.p\s*=\s*(?:[\w$]+\s*\+\s*)?(?:"([^"]*)"|'([^']*)')`)
+	if err != nil {
+		return scriptURL
+	}
+	if match := publicPathRegex.FindStringSubmatch(content); match != nil {
+		if publicPath, err := url.Parse(match[1] + match[2]); err == nil {
+			return scriptURL.ResolveReference(publicPath)
+		}
+	}
+	return scriptURL
+}
+
+// webpackChunkIDs returns the ids of the chunks a filename expression can
+// be built for: the keys of its maps and the ids of loaded chunks
+func webpackChunkIDs(terms []webpackTerm, loaded []string) []string {
+	seen := make(map[string]bool)
+	var ids []string
+
+	for _, term := range terms {
+		for id := range term.lookup {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range loaded {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// webpackChunkFilename evaluates a filename expression for a chunk id. It
+// fails if a map without fallback has no entry for the chunk
+func webpackChunkFilename(terms []webpackTerm, id string) (string, bool) {
+	var b strings.Builder
+	for _, term := range terms {
+		switch {
+		case term.id:
+			b.WriteString(id)
+		case term.lookup != nil:
+			value, ok := term.lookup[id]
+			if !ok && !term.fallback {
+				return "", false
+			}
+			if !ok {
+				value = id
+			}
+			b.WriteString(value)
+		default:
+			b.WriteString(term.literal)
+		}
+	}
+	return b.String(), true
+}
+
+// parseWebpackTerms parses a filename expression such as
+// "static/js/" + e + "." + {12:"abc123"}[e] + ".chunk.js", where param is
+// the name of the chunk id, up to the first part that isn't a term
+func parseWebpackTerms(s, param string) ([]webpackTerm, bool) {
+	var terms []webpackTerm
+
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+
+		var term webpackTerm
+		var ok bool
+		switch {
+		case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'"):
+			term.literal, s, ok = parseJSString(s)
+		case strings.HasPrefix(s, "({"):
+			// ({35:"vendors-lodash"}[e]||e)
+			term.lookup, s, ok = parseWebpackLookup(s[1:], param)
+			rest := strings.TrimLeft(s, " ")
+			if suffix := "||" + param + ")"; ok && strings.HasPrefix(strings.ReplaceAll(rest, " ", ""), suffix) {
+				term.fallback = true
+				s = rest[strings.Index(rest, ")")+1:]
+			} else {
+				ok = false
+			}
+		case strings.HasPrefix(s, "{"):
+			term.lookup, s, ok = parseWebpackLookup(s, param)
+		case strings.HasPrefix(s, param) && !isIdentByte(s, len(param)):
+			term.id, s, ok = true, s[len(param):], true
+		}
+		if !ok {
+			break
+		}
+		terms = append(terms, term)
+
+		s = strings.TrimLeft(s, " \t\r\n")
+		if !strings.HasPrefix(s, "+") {
+			break
+		}
+		s = s[1:]
+	}
+
+	return terms, len(terms) > 0
+}
+
+// parseWebpackLookup parses a map lookup such as {12:"abc123",34:"def"}[e]
+// and returns the map and the rest of s
+func parseWebpackLookup(s, param string) (map[string]string, string, bool) {
+	lookup := make(map[string]string)
+	s = strings.TrimPrefix(s, "{")
+
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		if strings.HasPrefix(s, "}") {
+			s = s[1:]
+			break
+		}
+
+		var key string
+		var ok bool
+		if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+			if key, s, ok = parseJSString(s); !ok {
+				return nil, s, false
+			}
+		} else {
+			end := strings.IndexAny(s, ":}")
+			if end <= 0 {
+				return nil, s, false
+			}
+			key, s = strings.TrimSpace(s[:end]), s[end:]
+			// chunk ids are numbers or names, anything else isn't a map
+			for i := range key {
+				if !isIdentByte(key, i) {
+					return nil, s, false
+				}
+			}
+		}
+
+		s = strings.TrimLeft(s, " ")
+		if !strings.HasPrefix(s, ":") {
+			return nil, s, false
+		}
+		value, rest, ok := parseJSString(strings.TrimLeft(s[1:], " "))
+		if !ok {
+			return nil, s, false
+		}
+		lookup[key], s = value, rest
+	}
+
+	index := "[" + param + "]"
+	if !strings.HasPrefix(s, index) {
+		return nil, s, false
+	}
+	return lookup, s[len(index):], true
+}
+
+// parseJSString parses a single or double quoted string literal without
+// escapes at the start of s and returns it and the rest of s
+func parseJSString(s string) (string, string, bool) {
+	if s == "" || s[0] != '"' && s[0] != '\'' {
+		return "", s, false
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end == -1 || strings.Contains(s[1:end+1], `\`) {
+		return "", s, false
+	}
+	return s[1 : end+1], s[end+2:], true
+}
+
+// isIdentByte reports whether s has an identifier character at i
+func isIdentByte(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestWebpackChunkURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		urls    []string
+	}{
+		{
+			name:    "webpack 5",
+			content: `__webpack_require__.p = "/";` + "\n" + `__webpack_require__.u = (chunkId) => "static/js/" + chunkId + "." + {12:"abc123",34:"def456"}[chunkId] + ".chunk.js";`,
+			urls:    []string{"https://example.com/static/js/12.abc123.chunk.js", "https://example.com/static/js/34.def456.chunk.js"},
+		},
+		{
+			name:    "minified with names",
+			content: `r.p="https://cdn.example.com/assets/",r.u=e=>"js/"+({35:"vendors-lodash"}[e]||e)+"."+{35:"aa11",36:"bb22"}[e]+".js"`,
+			urls:    []string{"https://cdn.example.com/assets/js/vendors-lodash.aa11.js", "https://cdn.example.com/assets/js/36.bb22.js"},
+		},
+		{
+			name:    "function with auto public path",
+			content: `r.p=e+"../";r.u=function(e){return"js/"+e+".js"};Promise.all([r.e(7),r.e("admin")])`,
+			urls:    []string{"https://example.com/static/js/7.js", "https://example.com/static/js/admin.js"},
+		},
+		{
+			name:    "webpack 5 unminified",
+			content: `__webpack_require__.p = "/";` + "\n" + `__webpack_require__.u = (chunkId) => {` + "\n\t" + `return "" + chunkId + "." + {"12":"abc","34":"def"}[chunkId] + ".js";` + "\n};",
+			urls:    []string{"https://example.com/12.abc.js", "https://example.com/34.def.js"},
+		},
+		{
+			name:    "block that isn't a chunk map",
+			content: `n.u=e=>{if(e){x:1}return e+".js"}`,
+		},
+		{
+			name:    "map with keys that aren't chunk ids",
+			content: `n.u=e=>e+"."+{return "" + e + ".":"x"}[e]+".js"`,
+		},
+		{
+			name:    "webpack 4",
+			content: `a.p="/";function c(e){return a.p+"static/js/"+({}[e]||e)+"."+{0:"f00",1:"ba4"}[e]+".chunk.js"}`,
+			urls:    []string{"https://example.com/static/js/0.f00.chunk.js", "https://example.com/static/js/1.ba4.chunk.js"},
+		},
+		{
+			name:    "relative to script",
+			content: `n.u=e=>e+"."+{5:"c0ffee"}[e]+".js"`,
+			urls:    []string{"https://example.com/static/js/5.c0ffee.js"},
+		},
+		{
+			name:    "other public path",
+			content: `x.p="/wrong/";n.p="/";n.u=e=>e+"."+{5:"c0ffee"}[e]+".js"`,
+			urls:    []string{"https://example.com/5.c0ffee.js"},
+		},
+		{
+			name:    "not a chunk builder",
+			content: `t.u=e=>e.value+1;o.u=function(e){return"#"+e}`,
+		},
+	}
+
+	scriptURL, _ := url.Parse("https://example.com/static/js/main.js")
+	for _, tt := range tests {
+		if urls := webpackChunkURLs(tt.content, scriptURL); !reflect.DeepEqual(urls, tt.urls) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.urls, urls)
+		}
+	}
+}
+
+func TestRunCrawlsWebpackChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		switch req.URL.Path {
+		case "/static/js/main.js":
+			fmt.Fprint(w, `r.p="/",r.u=e=>"static/js/"+e+"."+{42:"abc"}[e]+".chunk.js"`)
+		case "/static/js/42.abc.chunk.js":
+			fmt.Fprint(w, `const x = require("company-lazy-internals");`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	runner := newStubRegistry(t, nil)
+	runner.Options.CrawlDepth = 1
+	results := collectResults(runner)
+
+	runner.RunContext(context.Background(), server.URL+"/static/js/main.js")
+	runner.Close()
+
+	for _, result := range <-results {
+		if result.RequestURL != server.URL+"/static/js/42.abc.chunk.js" {
+			continue
+		}
+		if !reflect.DeepEqual(result.Referrers, []string{server.URL + "/static/js/main.js"}) {
+			t.Errorf("expected the runtime as referrer, got %v", result.Referrers)
+		}
+		if _, ok := findPackage(result.Packages, "company-lazy-internals"); !ok {
+			t.Errorf("expected company-lazy-internals, got %v", result.Packages)
+		}
+		return
+	}
+	t.Errorf("expected the chunk to be scanned")
+}
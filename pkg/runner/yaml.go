@@ -0,0 +1,99 @@
+package runner
+
+import "strings"
+
+// yamlLine is a key or list item on a line of a YAML file
+type yamlLine struct {
+	path        []string // keys leading to the line, ending with its own key
+	depth       int      // indentation in steps of two spaces
+	key         string   // key, unquoted, or the value of a list item
+	value       string   // value after the colon, unquoted
+	offset      int      // offset of key in the content
+	valueOffset int      // offset of value in the content
+}
+
+// scanYAML calls visit for every key and list item of a YAML document, with
+// the path of keys leading to it. It only understands the block style with
+// two space indentation that lockfiles and yarn's configuration are written
+// in, which is enough to find package names without a YAML parser
+func scanYAML(content string, visit func(line yamlLine)) {
+	var path []string
+
+	offset := 0
+	for _, raw := range strings.Split(content, "\n") {
+		lineStart := offset
+		offset += len(raw) + 1
+
+		line, ok := parseYAMLLine(raw, lineStart)
+		if !ok {
+			continue
+		}
+		path = append(path[:min(line.depth, len(path))], line.key)
+		line.path = path
+
+		visit(line)
+	}
+}
+
+// parseYAMLLine parses the key or list item on a line
+func parseYAMLLine(raw string, lineStart int) (yamlLine, bool) {
+	trimmed := strings.TrimLeft(raw, " ")
+	indent := len(raw) - len(trimmed)
+	trimmed = strings.TrimRight(trimmed, " \r")
+
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return yamlLine{}, false
+	}
+
+	line := yamlLine{depth: indent / 2, offset: lineStart + indent}
+
+	if strings.HasPrefix(trimmed, "- ") {
+		line.offset += 2
+		line.key = unquoteYAML(trimmed[2:])
+		if line.key != trimmed[2:] {
+			line.offset++
+		}
+		return line, true
+	}
+
+	rest := ""
+	if quote := trimmed[0]; quote == '\'' || quote == '"' {
+		end := strings.IndexByte(trimmed[1:], quote)
+		if end == -1 {
+			return yamlLine{}, false
+		}
+		line.key = trimmed[1 : end+1]
+		line.offset++
+		rest = trimmed[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return yamlLine{}, false
+		}
+		rest = rest[1:]
+	} else {
+		colon := strings.Index(trimmed, ": ")
+		switch {
+		case colon != -1:
+			line.key, rest = trimmed[:colon], trimmed[colon+1:]
+		case strings.HasSuffix(trimmed, ":"):
+			line.key = trimmed[:len(trimmed)-1]
+		default:
+			return yamlLine{}, false
+		}
+	}
+
+	value := strings.TrimSpace(rest)
+	line.value = unquoteYAML(value)
+	line.valueOffset = lineStart + indent + len(trimmed) - len(strings.TrimLeft(rest, " "))
+	if line.value != value {
+		line.valueOffset++
+	}
+	return line, true
+}
+
+// unquoteYAML removes the quotes around a single or double quoted scalar
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
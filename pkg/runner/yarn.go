@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// yarnBerryMetadataRegex matches the __metadata entry that sets Yarn 2+
+// lockfiles apart from the v1 format
+var yarnBerryMetadataRegex = regexp.MustCompile(`(?m)^__metadata:`)
+
+// yarnDependencyFields are the fields of lockfile entries and package
+// extensions that are keyed by package name
+var yarnDependencyFields = map[string]bool{
+	"dependencies":         true,
+	"peerDependencies":     true,
+	"optionalDependencies": true,
+	"dependenciesMeta":     true,
+	"peerDependenciesMeta": true,
+}
+
+func (r *Runner) isYarnrcFile(url string) bool {
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		url = url[:i]
+	}
+	return strings.HasSuffix(strings.ToLower(url), ".yarnrc.yml")
+}
+
+// extractFromYarnBerryLock finds the packages of a Yarn 2+ lockfile. Entries
+// are keyed by comma separated descriptors such as "lodash@npm:^4.17.0",
+// whose protocol tells where the package comes from: npm: from the registry,
+// possibly under an alias, patch: from another descriptor with a patch
+// applied, and workspace:, portal:, link: or file: from the project itself
+func (r *Runner) extractFromYarnBerryLock(content string) []Package {
+	var packages []Package
+
+	scanYAML(content, func(line yamlLine) {
+		if line.path[0] == "__metadata" {
+			return
+		}
+
+		switch {
+		case line.depth == 0:
+			start := 0
+			for _, descriptor := range strings.Split(line.key, ",") {
+				trimmed := strings.TrimLeft(descriptor, " ")
+				offset := line.offset + start + len(descriptor) - len(trimmed)
+				packages = append(packages, r.yarnDescriptorPackages(trimmed, "yarn-lock", offset)...)
+				start += len(descriptor) + 1
+			}
+		case line.depth == 1 && line.key == "resolution":
+			packages = append(packages, r.yarnDescriptorPackages(line.value, "yarn-lock", line.valueOffset)...)
+		case line.depth == 2 && yarnDependencyFields[line.path[1]]:
+			packages = append(packages, r.createPackageFromName(line.key, "yarn-lock-dependency", line.offset))
+			packages = append(packages, r.yarnRangePackages(line.value, "yarn-lock-dependency", line.valueOffset)...)
+		}
+	})
+
+	return packages
+}
+
+// extractFromYarnrc finds the scopes that a .yarnrc.yml routes through
+// npmScopes, which are reported as scope findings along with the registry
+// they are routed to, and the packages named by packageExtensions. Scopes
+// configured for a registry of their own are almost always private, which
+// makes them prime dependency confusion candidates
+func (r *Runner) extractFromYarnrc(content string) []Package {
+	var packages []Package
+	var defaultRegistry string
+	registries := make(map[string]string) // registry of each scope, by scope without @
+
+	scanYAML(content, func(line yamlLine) {
+		switch section := line.path[0]; {
+		case line.depth == 0 && line.key == "npmRegistryServer":
+			defaultRegistry = line.value
+		case section == "npmScopes" && line.depth == 1:
+			packages = append(packages, r.createScopeFromName(line.key, "yarnrc-scope", line.offset))
+		case section == "npmScopes" && line.depth == 2 && line.key == "npmRegistryServer":
+			registries[strings.TrimPrefix(line.path[1], "@")] = line.value
+		case section == "packageExtensions" && line.depth == 1:
+			packages = append(packages, r.yarnDescriptorPackages(line.key, "yarnrc-extension", line.offset)...)
+		case section == "packageExtensions" && line.depth == 3 && yarnDependencyFields[line.path[2]]:
+			packages = append(packages, r.createPackageFromName(line.key, "yarnrc-extension", line.offset))
+		}
+	})
+
+	for i := range packages {
+		if packages[i].IsScopeOnly() {
+			packages[i].ScopeRegistry = redactSecrets(defaultRegistry)
+			if registry, ok := registries[strings.TrimPrefix(packages[i].Namespace, "@")]; ok {
+				packages[i].ScopeRegistry = redactSecrets(registry)
+			}
+		}
+	}
+
+	return packages
+}
+
+// yarnDescriptorPackages returns the packages named by a descriptor such as
+// "@babel/core@npm:^7.0.0" found at offset: the package itself and, for
+// aliases and patches, the package the range refers to
+func (r *Runner) yarnDescriptorPackages(descriptor, method string, offset int) []Package {
+	at := strings.IndexByte(strings.TrimPrefix(descriptor, "@"), '@')
+	if at == -1 {
+		return []Package{r.createPackageFromName(descriptor, method, offset)}
+	}
+	if strings.HasPrefix(descriptor, "@") {
+		at++
+	}
+
+	packages := []Package{r.createPackageFromName(descriptor[:at], method, offset)}
+	return append(packages, r.yarnRangePackages(descriptor[at+1:], method, offsetAt(offset, at+1))...)
+}
+
+// yarnRangePackages returns the package a range found at offset refers to
+// when it isn't the package being described: the real package of an alias
+// such as npm:string-width@^4.2.0, or the patched package of a patch: range
+func (r *Runner) yarnRangePackages(rng, method string, offset int) []Package {
+	switch {
+	case strings.HasPrefix(rng, "npm:"):
+		// npm:^1.0.0 is a plain range, npm:name@^1.0.0 an alias
+		if alias := rng[len("npm:"):]; strings.IndexByte(alias, '@') > 0 || strings.HasPrefix(alias, "@") {
+			if name, ok := NormalizeSpecifier(alias); ok {
+				return []Package{r.createPackageFromName(name, method, offsetAt(offset, len("npm:")))}
+			}
+		}
+	case strings.HasPrefix(rng, "patch:"):
+		// patch:lodash@npm%3A4.17.21#~/.yarn/patches/lodash.patch::version=...
+		source := rng[len("patch:"):]
+		if i := strings.IndexByte(source, '#'); i != -1 {
+			source = source[:i]
+		}
+		if unescaped, err := url.PathUnescape(source); err == nil {
+			source = unescaped
+		}
+		return r.yarnDescriptorPackages(source, method, offsetAt(offset, len("patch:")))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package runner
+
+import "testing"
+
+func TestYarnrcScopes(t *testing.T) {
+	content := `npmRegistryServer: "https://npm.company.internal"
+
+npmScopes:
+  company:
+    npmAuthToken: "${NPM_TOKEN}"
+  "@partner":
+    npmRegistryServer: "https://npm.partner.example"
+`
+
+	runner := NewRunner()
+	packages := runner.extractPackages("https://example.com/.yarnrc.yml", "", []byte(content))
+
+	tests := []struct {
+		scope    string
+		registry string
+		line     int
+		column   int
+	}{
+		{"@company", "https://npm.company.internal", 4, 3},
+		{"@partner", "https://npm.partner.example", 6, 4},
+	}
+
+	for _, tt := range tests {
+		pkg, ok := findPackage(packages, tt.scope)
+		if !ok {
+			t.Errorf("%s not detected in %v", tt.scope, packages)
+			continue
+		}
+		if !pkg.IsScopeOnly() || pkg.ScopeRegistry != tt.registry {
+			t.Errorf("%s: expected a scope finding routed to %s, got %+v", tt.scope, tt.registry, pkg)
+		}
+		if e := pkg.Evidence[0]; e.Method != "yarnrc-scope" || e.Line != tt.line || e.Column != tt.column {
+			t.Errorf("%s: expected yarnrc-scope at %d:%d, got %s at %d:%d", tt.scope, tt.line, tt.column, e.Method, e.Line, e.Column)
+		}
+	}
+}
+
+func TestYarnBerryDescriptors(t *testing.T) {
+	content := "__metadata:\n  version: 8\n\n" +
+		"\"my-lodash@npm:lodash@^4.17.0, @scope/pkg@npm:^1.0.0\":\n" +
+		"  resolution: \"lodash@npm:4.17.21\"\n"
+
+	runner := NewRunner()
+	packages := runner.extractPackages("yarn.lock", "", []byte(content))
+
+	tests := []struct {
+		pkg    string
+		line   int
+		column int
+	}{
+		{"my-lodash", 4, 2},
+		{"lodash", 4, 16},
+		{"@scope/pkg", 4, 32},
+	}
+
+	for _, tt := range tests {
+		pkg, ok := findPackage(packages, tt.pkg)
+		if !ok {
+			t.Errorf("%s not detected in %v", tt.pkg, packages)
+			continue
+		}
+		if e := pkg.Evidence[0]; e.Method != "yarn-lock" || e.Line != tt.line || e.Column != tt.column {
+			t.Errorf("%s: expected yarn-lock at %d:%d, got %s at %d:%d", tt.pkg, tt.line, tt.column, e.Method, e.Line, e.Column)
+		}
+	}
+	if _, ok := findPackage(packages, "npm"); ok {
+		t.Errorf("expected no package for the npm: protocol, got %v", packages)
+	}
+}
@@ -0,0 +1,2 @@
+!function(){"use strict";var t=window.cart=function(){return 1}}();
+//# sourceMappingURL=data:application/json;charset=utf-8;base64,eyJ2ZXJzaW9uIjozLCJmaWxlIjoiaW5saW5lLmpzIiwic2VjdGlvbnMiOlt7Im9mZnNldCI6eyJsaW5lIjowLCJjb2x1bW4iOjB9LCJtYXAiOnsidmVyc2lvbiI6Mywic291cmNlcyI6WyJ3ZWJwYWNrOi8vc2hvcC8uL25vZGVfbW9kdWxlcy9jb21wYW55LWNhcnQtY29yZS9kaXN0L2luZGV4LmpzIiwid2VicGFjazovL3Nob3AvLi9zcmMvY2FydC5qcyJdLCJzb3VyY2VzQ29udGVudCI6WyJtb2R1bGUuZXhwb3J0cyA9IHt9OyIsImltcG9ydCB7IHRyYWNrIH0gZnJvbSAnY29tcGFueS1hbmFseXRpY3Mtc2RrJztcbmltcG9ydCBkZWJvdW5jZSBmcm9tICdsb2Rhc2guZGVib3VuY2UnO1xuZXhwb3J0IGNvbnN0IGNhcnQgPSAoKSA9PiB0cmFjaygnY2FydCcpO1xuIl0sIm5hbWVzIjpbXSwibWFwcGluZ3MiOiJBQUFBIn19LHsib2Zmc2V0Ijp7ImxpbmUiOjEsImNvbHVtbiI6MH0sIm1hcCI6eyJ2ZXJzaW9uIjozLCJzb3VyY2VzIjpbIndlYnBhY2s6Ly9zaG9wLy4vbm9kZV9tb2R1bGVzL0Bjb21wYW55L2NoZWNrb3V0LXdpZGdldHMvbGliL2luZGV4LmpzIl0sIm5hbWVzIjpbXSwibWFwcGluZ3MiOiJBQUFBIn19XX0=